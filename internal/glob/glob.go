@@ -0,0 +1,38 @@
+// Package glob matches strings against simple glob patterns, as used by the cache TTL
+// rules and the rate limiter policies.
+package glob
+
+// Match reports whether the string matches the glob pattern, where '*' matches any
+// sequence of bytes, including an empty one, and '?' matches exactly one byte. Every
+// other byte matches itself, and the pattern must match the whole string.
+//
+// Parameters:
+// - pattern (string): The glob pattern.
+// - s (string): The string to match.
+//
+// Returns:
+// - bool: True if the string matches the pattern.
+func Match(pattern, s string) bool {
+	p, i := 0, 0
+	star, backtrack := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, backtrack = p, i
+			p++
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case star >= 0:
+			// Let the last star absorb one more byte and retry.
+			backtrack++
+			p, i = star+1, backtrack
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,38 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/kmmania/er_commonlib/internal/glob"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{pattern: "", s: "", expected: true},
+		{pattern: "", s: "a", expected: false},
+		{pattern: "*", s: "", expected: true},
+		{pattern: "*", s: "anything", expected: true},
+		{pattern: "session:*", s: "session:42", expected: true},
+		{pattern: "session:*", s: "sessions:42", expected: false},
+		{pattern: "user:?", s: "user:1", expected: true},
+		{pattern: "user:?", s: "user:12", expected: false},
+		{pattern: "user:*:profile", s: "user:1:2:profile", expected: true},
+		{pattern: "user:*:profile", s: "user:1:settings", expected: false},
+		{pattern: "*a*b", s: "xaybzb", expected: true},
+		{pattern: "*a*b", s: "xaybzc", expected: false},
+		{pattern: "a**", s: "a", expected: true},
+		{pattern: "literal", s: "LITERAL", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+"|"+tc.s, func(t *testing.T) {
+			assert.Equal(t, tc.expected, glob.Match(tc.pattern, tc.s))
+		})
+	}
+}
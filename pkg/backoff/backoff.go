@@ -17,6 +17,7 @@ Constants:
 Functions:
   - RetryWithExponentialBackOff: Retries an operation with exponential backoff.
   - RetryOperationWithBackoff: Encapsulates retry logic with exponential backoff, including error handling and logging.
+  - NewExponentialBackOff: Returns the package's exponential backoff for custom retry loops.
 */
 package backoff
 
@@ -59,13 +60,24 @@ const (
 // Returns:
 // - error: An error if the operation fails after all retries, or nil if the operation succeeds.
 func RetryWithExponentialBackOff(ctx context.Context, operation func() error) error {
+	backoffCtx := backoff.WithContext(NewExponentialBackOff(), ctx)
+	return backoff.Retry(operation, backoffCtx)
+}
+
+// NewExponentialBackOff returns an exponential backoff configured with the package's intervals,
+// for callers driving their own retry loop.
+//
+// Returns:
+//   - *backoff.ExponentialBackOff: A backoff starting at BackoffInitialInterval, growing up to
+//     BackoffMaxInterval and stopping after BackoffMaxElapsedTime.
+func NewExponentialBackOff() *backoff.ExponentialBackOff {
 	expBackoff := backoff.NewExponentialBackOff()
 	expBackoff.InitialInterval = BackoffInitialInterval
 	expBackoff.MaxInterval = BackoffMaxInterval
 	expBackoff.MaxElapsedTime = BackoffMaxElapsedTime
-
-	backoffCtx := backoff.WithContext(expBackoff, ctx)
-	return backoff.Retry(operation, backoffCtx)
+	// The constructor reset the backoff with its own initial interval: start over with ours.
+	expBackoff.Reset()
+	return expBackoff
 }
 
 // RetryOperationWithBackoff is a utility function that encapsulates the retry logic with exponential backoff.
@@ -0,0 +1,20 @@
+package backoff_test
+
+import (
+	"testing"
+
+	"github.com/kmmania/er_commonlib/pkg/backoff"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExponentialBackOff(t *testing.T) {
+	expBackoff := backoff.NewExponentialBackOff()
+
+	// The first delay is the initial interval, randomized by up to half of it.
+	first := expBackoff.NextBackOff()
+	assert.GreaterOrEqual(t, first, backoff.BackoffInitialInterval/2)
+	assert.LessOrEqual(t, first, backoff.BackoffInitialInterval*3/2)
+	assert.Equal(t, backoff.BackoffMaxInterval, expBackoff.MaxInterval)
+	assert.Equal(t, backoff.BackoffMaxElapsedTime, expBackoff.MaxElapsedTime)
+}
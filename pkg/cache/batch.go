@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// BatchResult holds the outcome of a GetMany call, reported per key.
+type BatchResult struct {
+	// Hits maps each key found in the cache to its decoded value, as allocated by the destination factory.
+	Hits map[string]interface{}
+	// Misses lists, in request order, the keys absent from the cache or whose value could not be decoded.
+	Misses []string
+	// NotFound lists, in request order, the keys holding a tombstone written by SetNotFound.
+	NotFound []string
+}
+
+// GetMany retrieves several values from the cache in a single pipelined round trip.
+// Each hit is decoded into a new destination obtained from destFactory, which must return
+// a pointer (e.g. func() interface{} { return new(User) }). Misses are reported per key so
+// that callers can batch-load only the missing ones from the database.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - keys ([]string): The keys of the cache entries to retrieve.
+// - destFactory (func() interface{}): A function returning a new pointer to decode each hit into.
+//
+// Returns:
+// - *BatchResult: The hits, misses and known-missing keys.
+// - error: An error if the pipeline fails or times out; no partial result is returned in that case.
+func (cm *RedisCacheManager) GetMany(
+	ctx context.Context,
+	keys []string,
+	destFactory func() interface{},
+) (*BatchResult, error) {
+	values, _, err := cm.getManyWithTTL(ctx, keys, false)
+	if err != nil {
+		return nil, err
+	}
+	result := cm.decodeMany(keys, values, destFactory)
+
+	cm.logger.Debug("cache batch get",
+		zap.Int("keys", len(keys)),
+		zap.Int("hits", len(result.Hits)),
+		zap.Int("misses", len(result.Misses)),
+		zap.Int("notFound", len(result.NotFound)))
+	return result, nil
+}
+
+// getManyWithTTL retrieves the raw values stored under keys in a single pipelined round trip,
+// optionally with their remaining TTLs. Missing keys have a nil value.
+func (cm *RedisCacheManager) getManyWithTTL(
+	ctx context.Context,
+	keys []string,
+	withTTL bool,
+) ([][]byte, []time.Duration, error) {
+	values := make([][]byte, len(keys))
+	ttls := make([]time.Duration, len(keys))
+	if len(keys) == 0 {
+		return values, ttls, nil
+	}
+
+	// Create a new context with timeout.
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, CachedTimeout)
+	defer cancel()
+
+	// Queue one GET per key; the pipeline is sent in a single round trip.
+	pipe := cm.client.Pipeline()
+	gets := make([]func() ([]byte, error), len(keys))
+	pttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = cm.queueGet(ctxWithTimeout, pipe, key)
+		if withTTL {
+			pttls[i] = pipe.PTTL(ctxWithTimeout, cm.key(key))
+		}
+	}
+	if _, err := pipe.Exec(ctxWithTimeout); err != nil && !errors.Is(err, redis.Nil) {
+		cm.logger.Error("Error accessing Redis cache in batch", zap.Int("keys", len(keys)), zap.Error(err))
+		return nil, nil, err
+	}
+
+	for i := range keys {
+		if data, err := gets[i](); err == nil {
+			values[i] = data
+		}
+		if withTTL {
+			ttls[i] = pttls[i].Val()
+		}
+	}
+	return values, ttls, nil
+}
+
+// decodeMany decodes raw values read for keys into a BatchResult, in request order.
+func (cm *RedisCacheManager) decodeMany(keys []string, values [][]byte, destFactory func() interface{}) *BatchResult {
+	result := &BatchResult{Hits: make(map[string]interface{}, len(keys))}
+	for i, key := range keys {
+		data := values[i]
+		if data == nil {
+			result.Misses = append(result.Misses, key)
+			continue
+		}
+		if string(data) == tombstone {
+			result.NotFound = append(result.NotFound, key)
+			continue
+		}
+		dest := destFactory()
+		if err := cm.decode(data, dest); err != nil {
+			cm.logger.Error("Error unmarshalling cache data for key", zap.String("key", key), zap.Error(err))
+			result.Misses = append(result.Misses, key)
+			continue
+		}
+		result.Hits[key] = dest
+	}
+	return result
+}
+
+// SetMany stores several values in the cache with the same TTL in a single pipelined round trip.
+// Values that cannot be encoded are skipped and logged; the others are still written.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - values (map[string]interface{}): The values to cache, keyed by cache key.
+// - ttl (time.Duration): The time-to-live for the cached values.
+//
+// Returns:
+// - error: The first encoding or Redis error encountered, or nil if every value was written.
+func (cm *RedisCacheManager) SetMany(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	_, err := cm.setMany(ctx, values, ttl)
+	return err
+}
+
+// setMany encodes and stores the values in a single pipelined round trip, returning the
+// bytes stored for each key written successfully along with the first error encountered.
+func (cm *RedisCacheManager) setMany(
+	ctx context.Context,
+	values map[string]interface{},
+	ttl time.Duration,
+) (map[string][]byte, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	// Create a new context with timeout.
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, CachedTimeout)
+	defer cancel()
+
+	var firstErr error
+	encoded := make(map[string][]byte, len(values))
+	pipe := cm.client.Pipeline()
+	for key, value := range values {
+		data, _, err := cm.encode(value)
+		if err != nil {
+			cm.logger.Error("Error marshalling data for cache key", zap.String("key", key), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		encoded[key] = data
+		pipe.Set(ctxWithTimeout, cm.key(key), data, ttl)
+	}
+
+	if len(encoded) > 0 {
+		if _, err := pipe.Exec(ctxWithTimeout); err != nil {
+			cm.logger.Error("Error setting cache in batch", zap.Int("keys", len(encoded)), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	cm.logger.Debug("cache batch set", zap.Int("keys", len(values)), zap.Duration("ttl", ttl))
+	return encoded, firstErr
+}
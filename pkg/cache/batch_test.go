@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCacheManager_GetMany(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+
+	err := cm.SetMany(ctx, map[string]interface{}{
+		"user:1": user{ID: "1", Name: "Ada"},
+		"user:2": user{ID: "2", Name: "Grace"},
+	}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, server.TTL("user:1"))
+	assert.NoError(t, cm.SetNotFound(ctx, "user:3", time.Minute, time.Second))
+	assert.NoError(t, server.Set("user:5", "not json"))
+
+	result, err := cm.GetMany(ctx, []string{"user:1", "user:2", "user:3", "user:4", "user:5"}, func() interface{} {
+		return new(user)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"user:1": &user{ID: "1", Name: "Ada"},
+		"user:2": &user{ID: "2", Name: "Grace"},
+	}, result.Hits)
+	assert.Equal(t, []string{"user:4", "user:5"}, result.Misses)
+	assert.Equal(t, []string{"user:3"}, result.NotFound)
+}
+
+func TestRedisCacheManager_GetMany_RedisDown(t *testing.T) {
+	cm, server := setUpRedis(t)
+	server.Close()
+
+	result, err := cm.GetMany(context.Background(), []string{"user:1"}, func() interface{} { return new(user) })
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
+
+func TestRedisCacheManager_SetMany_EncodingError(t *testing.T) {
+	cm, server := setUpRedis(t)
+
+	err := cm.SetMany(context.Background(), map[string]interface{}{
+		"ok":  user{ID: "1"},
+		"bad": make(chan int),
+	}, time.Minute)
+	assert.Error(t, err)
+	assert.True(t, server.Exists("ok"))
+	assert.False(t, server.Exists("bad"))
+}
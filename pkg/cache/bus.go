@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/backoff"
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DefaultInvalidationChannel is the Redis pub/sub channel used by an InvalidationBus when none is given.
+const DefaultInvalidationChannel = "cache:invalidate"
+
+// Invalidation describes the cache entries to evict from the in-process tier of every instance.
+type Invalidation struct {
+	Keys     []string `json:"keys,omitempty"`     // Keys to evict
+	Prefixes []string `json:"prefixes,omitempty"` // Key prefixes whose entries should all be evicted
+	Flush    bool     `json:"-"`                  // Evict every entry; set locally when messages may have been missed
+}
+
+// invalidationMessage is the payload published on the invalidation channel.
+type invalidationMessage struct {
+	Origin string `json:"origin"`
+	Invalidation
+}
+
+// InvalidationBus broadcasts cache invalidations between instances over a Redis pub/sub channel.
+//
+// Pub/sub delivery is at-most-once: messages published while an instance is disconnected are lost.
+// Subscribe therefore reports a Flush invalidation every time the subscription is (re)established,
+// so that subscribers drop whatever they may have missed.
+type InvalidationBus struct {
+	// client is the Redis client used to publish and subscribe.
+	client redis.UniversalClient
+	// channel is the Redis pub/sub channel carrying invalidations.
+	channel string
+	// origin identifies this bus, so that it ignores its own messages.
+	origin string
+	// logger provides structured logging for this bus's operations.
+	logger logger.Logger
+}
+
+// NewInvalidationBus creates and returns a new InvalidationBus.
+//
+// Parameters:
+// - client (redis.UniversalClient): The Redis client used to publish and subscribe.
+// - channel (string): The Redis pub/sub channel; DefaultInvalidationChannel if empty.
+// - logger (logger.Logger): A logger instance for logging bus activities and errors.
+//
+// Returns:
+// - *InvalidationBus: An initialized InvalidationBus.
+func NewInvalidationBus(client redis.UniversalClient, channel string, logger logger.Logger) *InvalidationBus {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+	return &InvalidationBus{
+		client:  client,
+		channel: channel,
+		origin:  newOrigin(),
+		logger:  logger,
+	}
+}
+
+// newOrigin returns a random identifier for a bus.
+func newOrigin() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Publish broadcasts an invalidation to the other instances subscribed to the channel.
+// Invalidations without keys or prefixes are not published.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+// - invalidation (Invalidation): The entries to evict.
+//
+// Returns:
+// - error: An error if the message cannot be encoded or published.
+func (b *InvalidationBus) Publish(ctx context.Context, invalidation Invalidation) error {
+	if len(invalidation.Keys) == 0 && len(invalidation.Prefixes) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(invalidationMessage{Origin: b.origin, Invalidation: invalidation})
+	if err != nil {
+		return err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, CachedTimeout)
+	defer cancel()
+	return b.client.Publish(ctxWithTimeout, b.channel, payload).Err()
+}
+
+// Subscribe listens on the channel and calls handler for every invalidation published by
+// other instances. It blocks until the context is cancelled, resubscribing with the
+// exponential backoff of package backoff, without time limit, whenever the connection is
+// lost, and calls handler with a Flush invalidation each time the subscription is established.
+//
+// Parameters:
+// - ctx (context.Context): The context controlling the lifetime of the subscription.
+// - handler (func(Invalidation)): The function applying invalidations; called from a single goroutine.
+//
+// Returns:
+// - error: The context's error once it is cancelled.
+func (b *InvalidationBus) Subscribe(ctx context.Context, handler func(Invalidation)) error {
+	retry := backoff.NewExponentialBackOff()
+	retry.MaxElapsedTime = 0
+
+	for {
+		err := b.receive(ctx, handler, retry.Reset)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay := retry.NextBackOff()
+		b.logger.Warn("Cache invalidation subscription lost, resubscribing",
+			zap.String("channel", b.channel), zap.Duration("delay", delay), zap.Error(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// receive subscribes to the channel and dispatches messages until the connection fails
+// or the context is cancelled. onSubscribed is called once the subscription is confirmed.
+func (b *InvalidationBus) receive(ctx context.Context, handler func(Invalidation), onSubscribed func()) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer func() { _ = pubsub.Close() }()
+	// Receive blocks on the connection regardless of the context, so closing the
+	// subscription is what interrupts it on cancellation.
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer stop()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			onSubscribed()
+			b.logger.Info("Subscribed to cache invalidations", zap.String("channel", b.channel))
+			handler(Invalidation{Flush: true})
+		case *redis.Message:
+			var message invalidationMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				b.logger.Warn("Failed to decode cache invalidation", zap.String("channel", b.channel), zap.Error(err))
+				continue
+			}
+			if message.Origin == b.origin {
+				continue
+			}
+			handler(message.Invalidation)
+		}
+	}
+}
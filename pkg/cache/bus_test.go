@@ -0,0 +1,132 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newBusTiered returns a Tiered cache sharing the server with other instances through an invalidation bus.
+func newBusTiered(server *miniredis.Miniredis) *cache.Tiered {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	bus := cache.NewInvalidationBus(client, "", zap.NewNop())
+	return cache.NewTiered(cache.New(client, zap.NewNop()), cache.TieredConfig{Bus: bus}, zap.NewNop())
+}
+
+// listen runs the instance's subscription until the test ends, and waits for it to be established.
+func listen(t *testing.T, server *miniredis.Miniredis, instances ...*cache.Tiered) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	for _, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = instance.Listen(ctx)
+		}()
+	}
+	waitForSubscribers(t, server, len(instances))
+}
+
+func waitForSubscribers(t *testing.T, server *miniredis.Miniredis, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return server.PubSubNumSub(cache.DefaultInvalidationChannel)[cache.DefaultInvalidationChannel] == n
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestInvalidationBus_PublishSubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	publisher := cache.NewInvalidationBus(client, "invalidations", zap.NewNop())
+	subscriber := cache.NewInvalidationBus(client, "invalidations", zap.NewNop())
+
+	received := make(chan cache.Invalidation, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- subscriber.Subscribe(ctx, func(inv cache.Invalidation) { received <- inv }) }()
+
+	assert.Equal(t, cache.Invalidation{Flush: true}, <-received)
+
+	// A bus ignores its own messages.
+	assert.NoError(t, subscriber.Publish(ctx, cache.Invalidation{Keys: []string{"ignored"}}))
+	assert.NoError(t, publisher.Publish(ctx, cache.Invalidation{Keys: []string{"user:1"}, Prefixes: []string{"search:"}}))
+	assert.Equal(t, cache.Invalidation{Keys: []string{"user:1"}, Prefixes: []string{"search:"}}, <-received)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, received)
+}
+
+func TestTiered_Bus(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	log := zap.NewNop()
+	a, b := newBusTiered(server), newBusTiered(server)
+	listen(t, server, a, b)
+
+	// Warm b's in-process tier.
+	a.SetCache(ctx, "user:1", user{ID: "1"}, log, "users")
+	a.SetCache(ctx, "user:2", user{ID: "2"}, log)
+	a.SetCache(ctx, "search:1", user{ID: "3"}, log)
+	var got user
+	for _, key := range []string{"user:1", "user:2", "search:1"} {
+		require.NoError(t, b.Get(ctx, key, &got, time.Second))
+	}
+
+	// A write on a evicts b's stale copy.
+	a.Set(ctx, "user:2", user{ID: "2", Name: "Grace"}, time.Minute, time.Second)
+	assert.Eventually(t, func() bool {
+		return b.Get(ctx, "user:2", &got, time.Second) == nil && got.Name == "Grace"
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, a.InvalidateTag(ctx, "users", log))
+	assert.NoError(t, a.InvalidatePrefix(ctx, "search:", log))
+	for _, key := range []string{"user:1", "search:1"} {
+		assert.Eventually(t, func() bool {
+			return b.Get(ctx, key, &got, time.Second) != nil
+		}, time.Second, 10*time.Millisecond, key)
+	}
+}
+
+func TestTiered_Bus_Resubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+	a, b := newBusTiered(server), newBusTiered(server)
+	listen(t, server, b)
+
+	a.Set(ctx, "user:1", user{ID: "1"}, time.Minute, time.Second)
+	var got user
+	require.NoError(t, b.Get(ctx, "user:1", &got, time.Second))
+
+	// Invalidations published while b is disconnected are lost, so b drops its
+	// in-process tier once it has resubscribed.
+	server.Close()
+	require.NoError(t, server.Restart())
+	a.Set(ctx, "user:1", user{ID: "1", Name: "Ada"}, time.Minute, time.Second)
+	waitForSubscribers(t, server, 1)
+
+	assert.Eventually(t, func() bool {
+		return b.Get(ctx, "user:1", &got, time.Second) == nil && got.Name == "Ada"
+	}, time.Second, 10*time.Millisecond)
+
+	// And invalidations flow again.
+	a.Set(ctx, "user:1", user{ID: "1", Name: "Grace"}, time.Minute, time.Second)
+	assert.Eventually(t, func() bool {
+		return b.Get(ctx, "user:1", &got, time.Second) == nil && got.Name == "Grace"
+	}, time.Second, 10*time.Millisecond)
+}
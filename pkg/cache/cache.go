@@ -7,11 +7,11 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/kmmania/er_commonlib/pkg/logger"
+	"github.com/kmmania/er_commonlib/pkg/repository"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -28,8 +28,18 @@ const (
 	// CachedTimeout represents the maximum time to wait for a cache operation (e.g., retrieving an item)
 	// Set to 2 seconds.
 	CachedTimeout = 2 * time.Second
+
+	// NotFoundLifetime represents the duration for which a "known missing" tombstone remains valid.
+	// It is deliberately short so that newly created records become visible quickly.
+	// Set to 1 minute.
+	NotFoundLifetime = 1 * time.Minute
 )
 
+// tombstone is the raw value stored in Redis to record that a key is known to be missing.
+// It uses the value header with the reserved tombstoneTag, which no codec is allowed to use,
+// so the marker cannot collide with a real cached value.
+const tombstone = "\x00NF"
+
 // RedisCache defines the contract for interacting with a Redis-based cache.
 // It provides methods to perform common cache operations like retrieving, storing, and invalidating data.
 type RedisCache interface {
@@ -38,7 +48,7 @@ type RedisCache interface {
 	Get(ctx context.Context, key string, dest interface{}, timeout time.Duration) error
 
 	// Set sets a value in the cache with a specified TTL, applying a timeout.
-	Set(ctx context.Context, key string, value interface{}, ttl time.Duration, timeout time.Duration)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration, timeout time.Duration) error
 
 	// Delete removes a value from the cache by its key, applying a timeout.
 	Delete(ctx context.Context, key string, timeout time.Duration) error
@@ -46,35 +56,112 @@ type RedisCache interface {
 	// GetFromCache retrieves data from the cache if it exists.
 	GetFromCache(ctx context.Context, key string, logger logger.Logger, target interface{}) (bool, error)
 
-	// SetCache stores data in the cache with the specified key.
-	SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger)
+	// SetCache stores data in the cache with the specified key, associating it with optional tags.
+	SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger, tags ...string) error
 
 	// InvalidateCache removes cached data for a specific key.
 	InvalidateCache(ctx context.Context, key string, logger logger.Logger) error
+
+	// SetNotFound stores a tombstone recording that the key is known to be missing, applying a timeout.
+	SetNotFound(ctx context.Context, key string, ttl time.Duration, timeout time.Duration) error
+
+	// SetNotFoundCache stores a tombstone for the specified key with the NotFoundLifetime TTL.
+	SetNotFoundCache(ctx context.Context, key string, logger logger.Logger) error
+
+	// GetMany retrieves several values from the cache in a single round trip, reporting misses per key.
+	GetMany(ctx context.Context, keys []string, destFactory func() interface{}) (*BatchResult, error)
+
+	// SetMany stores several values in the cache with the same TTL in a single round trip.
+	SetMany(ctx context.Context, values map[string]interface{}, ttl time.Duration) error
+
+	// InvalidateTag removes every cached entry associated with a tag.
+	InvalidateTag(ctx context.Context, tag string, logger logger.Logger) error
+
+	// InvalidatePrefix removes every cached entry whose key starts with a prefix.
+	InvalidatePrefix(ctx context.Context, prefix string, logger logger.Logger) error
 }
 
 // RedisCacheManager is a struct that manages interactions with a Redis cache instance.
 // It provides methods to retrieve, store, and delete data in the cache.
 type RedisCacheManager struct {
-	// client is a pointer to the Redis client used for executing cache operations.
-	client *redis.Client
+	// client is the Redis client used for executing cache operations.
+	client redis.UniversalClient
 	// logger provides structured logging for this RedisCacheManager's operations.
 	logger logger.Logger
+	// codec encodes values written to the cache.
+	codec Codec
+	// codecs holds every codec able to decode values read from the cache, keyed by tag.
+	codecs map[byte]Codec
+	// compression is the algorithm used to compress large values, if any.
+	compression Compression
+	// compressionThreshold is the minimum encoded size in bytes for a value to be compressed.
+	compressionThreshold int
+	// keyPrefix is prepended to every key written to or read from Redis.
+	keyPrefix string
+	// ttlPolicy decides the TTL of keys written by SetCache, if set.
+	ttlPolicy *TTLPolicy
+}
+
+// Option configures optional behaviour of a RedisCacheManager.
+type Option func(*RedisCacheManager)
+
+// WithCodec sets the codec used to encode values written to the cache.
+// Values already stored with any built-in codec, or with the given codec, remain readable.
+// It panics if a custom codec uses the tag reserved for tombstones or a built-in codec's tag.
+//
+// Parameters:
+// - codec (Codec): The codec used to encode new values. Defaults to JSONCodec.
+//
+// Returns:
+// - Option: An option to pass to New.
+func WithCodec(codec Codec) Option {
+	checkCodecTag(codec)
+	return func(cm *RedisCacheManager) {
+		cm.codec = codec
+		cm.codecs[codec.Tag()] = codec
+	}
+}
+
+// WithKeyPrefix prepends a global prefix to every Redis key used by the manager, including
+// tag sets, so that several services can share one Redis without their keys colliding.
+// Keys passed to and returned by the manager's methods never include the prefix.
+//
+// Parameters:
+// - prefix (string): The prefix, typically a service namespace ending in a separator, e.g. "billing:".
+//
+// Returns:
+// - Option: An option to pass to New.
+func WithKeyPrefix(prefix string) Option {
+	return func(cm *RedisCacheManager) {
+		cm.keyPrefix = prefix
+	}
+}
+
+// key returns the Redis key under which the given cache key is stored.
+func (cm *RedisCacheManager) key(key string) string {
+	return cm.keyPrefix + key
 }
 
 // New creates and returns a new RedisCacheManager instance.
 //
 // Parameters:
-// - client (*redis.Client): The Redis client used to interact with the Redis server.
+// - client (redis.UniversalClient): The Redis client used to interact with the Redis server.
 // - logger (*zap.Logger): A logger instance for logging server activities and errors.
+// - opts (...Option): Optional settings such as the codec or compression to use.
 //
 // Returns:
 // - *RedisCacheManager: An initialized RedisCacheManager.
-func New(client *redis.Client, logger logger.Logger) *RedisCacheManager {
-	return &RedisCacheManager{
+func New(client redis.UniversalClient, logger logger.Logger, opts ...Option) *RedisCacheManager {
+	cm := &RedisCacheManager{
 		client: client,
 		logger: logger,
+		codec:  JSONCodec{},
+		codecs: builtinCodecs(),
 	}
+	for _, opt := range opts {
+		opt(cm)
+	}
+	return cm
 }
 
 // Get retrieves a value from the cache by its key, applying a timeout.
@@ -83,18 +170,19 @@ func New(client *redis.Client, logger logger.Logger) *RedisCacheManager {
 // Parameters:
 // - ctx (context.Context): The base context for the operation.
 // - key (string): The key of the cache entry to retrieve.
-// - dest (interface{}): A pointer to the variable where the retrieved value should be unmarshalled.
+// - dest (interface{}): A pointer to the variable where the retrieved value should be decoded.
 // - timeout (time.Duration): The maximum time allowed for the operation.
 //
 // Returns:
-// - error: An error if the operation fails, times out, or the key is not found.
+//   - error: An error if the operation fails, times out, or the key is not found.
+//     repository.ErrNotFound is returned if the key holds a tombstone written by SetNotFound.
 func (cm *RedisCacheManager) Get(ctx context.Context, key string, dest interface{}, timeout time.Duration) error {
 	// Create a new context with timeout.
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel() // Ensure the context is cancelled after the operation
 
 	// Try to retrieve the value from Redis.
-	data, err := cm.client.Get(ctxWithTimeout, key).Result()
+	data, err := cm.queueGet(ctxWithTimeout, cm.client, key)()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			cm.logger.Debug("cache miss", zap.String("key", key), zap.Duration("timeout", timeout))
@@ -104,8 +192,20 @@ func (cm *RedisCacheManager) Get(ctx context.Context, key string, dest interface
 		return err
 	}
 
-	// Attempt to unmarshal the JSON data into the destination variable.
-	err = json.Unmarshal([]byte(data), dest)
+	return cm.decodeValue(key, data, dest)
+}
+
+// decodeValue decodes a raw value read from the cache into dest, reporting tombstones
+// as repository.ErrNotFound.
+func (cm *RedisCacheManager) decodeValue(key string, data []byte, dest interface{}) error {
+	// The key is known to be missing from the underlying data store.
+	if string(data) == tombstone {
+		cm.logger.Debug("cache tombstone hit", zap.String("key", key))
+		return repository.ErrNotFound
+	}
+
+	// Attempt to decode the data into the destination variable.
+	err := cm.decode(data, dest)
 	if err != nil {
 		cm.logger.Error("Error unmarshalling cache data for key", zap.Error(err))
 		return err
@@ -124,30 +224,90 @@ func (cm *RedisCacheManager) Get(ctx context.Context, key string, dest interface
 // - timeout (time.Duration): The maximum time allowed for the operation.
 //
 // Returns:
-// - error: An error if the operation fails or times out.
+// - error: An error if the value cannot be encoded, or if the operation fails or times out.
 func (cm *RedisCacheManager) Set(
 	ctx context.Context,
 	key string,
 	value interface{},
 	ttl time.Duration,
 	timeout time.Duration,
-) {
+) error {
+	_, err := cm.set(ctx, key, value, ttl, timeout, nil)
+	return err
+}
+
+// set encodes and stores a value, associating the key with the given tags in the same round trip.
+// It logs failures and returns the stored bytes on success.
+func (cm *RedisCacheManager) set(
+	ctx context.Context,
+	key string,
+	value interface{},
+	ttl time.Duration,
+	timeout time.Duration,
+	tags []string,
+) ([]byte, error) {
 	// Create a new context with timeout.
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Marshal the value to JSON.
-	data, err := json.Marshal(value)
+	// Encode the value with the configured codec.
+	data, size, err := cm.encode(value)
 	if err != nil {
 		cm.logger.Error("Error marshalling data for cache key", zap.String("key", key), zap.Error(err))
-		return
+		return nil, err
 	}
 
-	// Perform the Redis Set operation.
-	err = cm.client.Set(ctxWithTimeout, key, data, ttl).Err()
+	// Perform the Redis Set operation. Tags are recorded first so that an invalidation
+	// racing with this write can never miss the key.
+	if len(tags) == 0 {
+		err = cm.client.Set(ctxWithTimeout, cm.key(key), data, ttl).Err()
+	} else {
+		pipe := cm.client.Pipeline()
+		cm.queueTags(ctxWithTimeout, pipe, key, ttl, tags)
+		pipe.Set(ctxWithTimeout, cm.key(key), data, ttl)
+		_, err = pipe.Exec(ctxWithTimeout)
+	}
 	if err != nil {
 		cm.logger.Error("Error setting cache for key", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	cm.logger.Debug("cache set",
+		zap.String("key", key),
+		zap.Duration("ttl", ttl),
+		zap.Strings("tags", tags),
+		zap.Int("size", size),
+		zap.Int("storedSize", len(data)),
+		zap.Bool("compressed", data[0] == compressedMagic),
+		zap.Float64("ratio", float64(len(data))/float64(max(size, 1))))
+	return data, nil
+}
+
+// getWithTTL retrieves the raw value stored under key together with its remaining TTL,
+// in a single round trip. The TTL is negative if the key has no expiry.
+func (cm *RedisCacheManager) getWithTTL(
+	ctx context.Context,
+	key string,
+	timeout time.Duration,
+) ([]byte, time.Duration, error) {
+	// Create a new context with timeout.
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pipe := cm.client.Pipeline()
+	get := cm.queueGet(ctxWithTimeout, pipe, key)
+	pttl := pipe.PTTL(ctxWithTimeout, cm.key(key))
+	if _, err := pipe.Exec(ctxWithTimeout); err != nil && !errors.Is(err, redis.Nil) {
+		cm.logger.Error("Error accessing Redis cache", zap.Error(err))
+		return nil, 0, err
+	}
+
+	data, err := get()
+	if errors.Is(err, redis.Nil) {
+		cm.logger.Debug("cache miss", zap.String("key", key), zap.Duration("timeout", timeout))
+		return nil, 0, ErrCacheMiss
 	}
+	return data, pttl.Val(), err
 }
 
 // Delete removes a value from the cache by its key, applying a timeout.
@@ -165,19 +325,20 @@ func (cm *RedisCacheManager) Delete(ctx context.Context, key string, timeout tim
 	defer cancel()
 
 	// Perform the Redis Delete operation.
-	err := cm.client.Del(ctxWithTimeout, key).Err()
+	err := cm.client.Del(ctxWithTimeout, cm.key(key)).Err()
 	if err != nil {
 		cm.logger.Error("Error deleting cache for key", zap.String("key", key), zap.Error(err))
-	} else {
-		cm.logger.Info("Cache invalidated for key", zap.String("key", key))
+		return err
 	}
 
+	cm.logger.Info("Cache invalidated for key", zap.String("key", key))
 	return nil
 }
 
 // GetFromCache retrieves data from the cache if it exists.
 // It checks the cache for the given key and populates the target object if the data is found.
 // If the data is not found (cache miss), it returns false with no error.
+// If the key holds a tombstone, it returns false with repository.ErrNotFound.
 // If a Redis error occurs, it returns false with the error.
 //
 // Parameters:
@@ -195,30 +356,63 @@ func (cm *RedisCacheManager) GetFromCache(
 	logger logger.Logger,
 	target interface{},
 ) (bool, error) {
-	err := cm.Get(ctx, key, target, CachedTimeout)
+	return getFromCache(ctx, cm, key, logger, target)
+}
+
+// getFromCache implements GetFromCache on top of the Get method of any RedisCache.
+func getFromCache(
+	ctx context.Context,
+	cache RedisCache,
+	key string,
+	logger logger.Logger,
+	target interface{},
+) (bool, error) {
+	err := cache.Get(ctx, key, target, CachedTimeout)
 	if err == nil {
 		logger.Info("Response from cache", zap.String("cacheKey", key))
 		return true, nil
 	} else if errors.Is(err, ErrCacheMiss) {
 		logger.Info("Cache miss", zap.String("cacheKey", key))
 		return false, nil
+	} else if errors.Is(err, repository.ErrNotFound) {
+		logger.Info("Cached not found", zap.String("cacheKey", key))
+		return false, err
 	} else {
 		logger.Error("Cache access error", zap.Error(err))
 		return false, err
 	}
 }
 
-// SetCache stores data in the cache with the specified key.
-// It logs the operation and does not return an error, as the underlying cache manager's Set method does not return one.
+// SetCache stores data in the cache with the specified key, for the TTL decided by the
+// TTL policy, or CachedLifetime if none is configured.
+// It logs the operation and returns an error if the data could not be cached.
+// The key can be associated with tags, so that it is removed by a later InvalidateTag call for any of them.
+// A key with a sliding TTL stays in its tag sets for SlidingTagLifetimeFactor times its TTL
+// after this call: a key kept alive by reads for longer, without being written again, must
+// be removed with InvalidateCache or InvalidatePrefix instead.
 //
 // Parameters:
 //   - ctx (context.Context): The context for the cache operation.
 //   - key (string): The key under which the data will be stored in the cache.
 //   - data (interface{}): The data to be stored in the cache.
 //   - logger (logger.Logger): The logger instance for logging cache operations.
-func (cm *RedisCacheManager) SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger) {
-	cm.Set(ctx, key, data, CachedLifetime, CachedTimeout)
+//   - tags (...string): The tags to associate with the key, e.g. "user:42" for every view embedding that user.
+//
+// Returns:
+//   - error: An error if the data cannot be encoded or stored, or nil if successful.
+func (cm *RedisCacheManager) SetCache(
+	ctx context.Context,
+	key string,
+	data interface{},
+	logger logger.Logger,
+	tags ...string,
+) error {
+	if _, err := cm.set(ctx, key, data, cm.ttl(key), CachedTimeout, tags); err != nil {
+		logger.Error("Failed to cache data", zap.String("cacheKey", key), zap.Error(err))
+		return err
+	}
 	logger.Info("Data cached successfully", zap.String("cacheKey", key))
+	return nil
 }
 
 // InvalidateCache removes cached data for a specific key.
@@ -240,3 +434,53 @@ func (cm *RedisCacheManager) InvalidateCache(ctx context.Context, key string, lo
 	logger.Info("Cache invalidated successfully", zap.String("cacheKey", key))
 	return nil
 }
+
+// SetNotFound stores a tombstone recording that the key is known to be missing, applying a timeout.
+// Subsequent calls to Get for the key return repository.ErrNotFound until the TTL expires
+// or the key is overwritten by Set.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The cache key.
+// - ttl (time.Duration): The time-to-live for the tombstone.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - error: An error if the tombstone could not be written, or nil if successful.
+func (cm *RedisCacheManager) SetNotFound(ctx context.Context, key string, ttl time.Duration, timeout time.Duration) error {
+	return cm.setNotFound(ctx, key, ttl, timeout)
+}
+
+// setNotFound stores a tombstone for the key, logging and returning any failure.
+func (cm *RedisCacheManager) setNotFound(ctx context.Context, key string, ttl time.Duration, timeout time.Duration) error {
+	// Create a new context with timeout.
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Perform the Redis Set operation with the raw tombstone marker.
+	err := cm.client.Set(ctxWithTimeout, cm.key(key), tombstone, ttl).Err()
+	if err != nil {
+		cm.logger.Error("Error setting cache tombstone for key", zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+// SetNotFoundCache stores a tombstone for the specified key with the NotFoundLifetime TTL.
+// It is typically called when a lookup returned repository.ErrNotFound, so that repeated
+// lookups for nonexistent records are answered by the cache instead of the database.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key under which the tombstone will be stored in the cache.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the tombstone could not be written, or nil if successful.
+func (cm *RedisCacheManager) SetNotFoundCache(ctx context.Context, key string, logger logger.Logger) error {
+	if err := cm.SetNotFound(ctx, key, NotFoundLifetime, CachedTimeout); err != nil {
+		logger.Error("Failed to cache not found", zap.String("cacheKey", key), zap.Error(err))
+		return err
+	}
+	logger.Info("Not found cached successfully", zap.String("cacheKey", key))
+	return nil
+}
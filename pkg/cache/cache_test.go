@@ -8,9 +8,13 @@ import (
 
 	"github.com/kmmania/er_commonlib/pkg/cache"
 	mocks "github.com/kmmania/er_commonlib/pkg/mocks/cache"
+	logmocks "github.com/kmmania/er_commonlib/pkg/mocks/logger"
+	"github.com/kmmania/er_commonlib/pkg/repository"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
 type testEnv struct {
@@ -31,6 +35,13 @@ func tearDownTestEnv(env *testEnv) {
 	env.ctrl.Finish()
 }
 
+// setUpRedis starts an in-process Redis server and returns a RedisCacheManager connected to it.
+func setUpRedis(t *testing.T) (*cache.RedisCacheManager, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	return newManager(server), server
+}
+
 func TestRedisCache_Get(t *testing.T) {
 	env := setUpTestEnv(t)
 	defer tearDownTestEnv(env)
@@ -180,3 +191,71 @@ func TestRedisCache_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisCacheManager_NotFound(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+
+	t.Run("Tombstone surfaces ErrNotFound", func(t *testing.T) {
+		assert.NoError(t, cm.SetNotFoundCache(ctx, "user:404", zap.NewNop()))
+
+		var result map[string]interface{}
+		err := cm.Get(ctx, "user:404", &result, time.Second)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+
+		found, err := cm.GetFromCache(ctx, "user:404", zap.NewNop(), &result)
+		assert.False(t, found)
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+		assert.Equal(t, cache.NotFoundLifetime, server.TTL("user:404"))
+	})
+
+	t.Run("Real value overwrites tombstone", func(t *testing.T) {
+		cm.Set(ctx, "user:404", "found", time.Minute, time.Second)
+
+		var result string
+		err := cm.Get(ctx, "user:404", &result, time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, "found", result)
+	})
+
+	t.Run("Plain miss is not a tombstone", func(t *testing.T) {
+		var result string
+		err := cm.Get(ctx, "user:missing", &result, time.Second)
+		assert.ErrorIs(t, err, cache.ErrCacheMiss)
+	})
+}
+
+func TestRedisCacheManager_WriteErrors(t *testing.T) {
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	t.Run("Set reports encoding errors", func(t *testing.T) {
+		cm, server := setUpRedis(t)
+		assert.Error(t, cm.Set(ctx, "bad", make(chan int), time.Minute, time.Second))
+		assert.Error(t, cm.SetCache(ctx, "bad", make(chan int), log))
+		assert.False(t, server.Exists("bad"))
+	})
+
+	t.Run("Writes and deletes report Redis errors", func(t *testing.T) {
+		cm, server := setUpRedis(t)
+		assert.NoError(t, cm.Set(ctx, "user:1", "cached", time.Minute, time.Second))
+		server.Close()
+
+		assert.Error(t, cm.Set(ctx, "user:1", "cached", time.Minute, time.Second))
+		assert.Error(t, cm.SetCache(ctx, "user:1", "cached", log))
+		assert.Error(t, cm.Delete(ctx, "user:1", time.Second))
+		assert.Error(t, cm.InvalidateCache(ctx, "user:1", log))
+		assert.Error(t, cm.SetNotFound(ctx, "user:1", time.Minute, time.Second))
+	})
+
+	t.Run("Failed tombstones are not logged as cached", func(t *testing.T) {
+		cm, server := setUpRedis(t)
+		server.Close()
+
+		// Only the failure is logged: an unexpected Info call fails the test.
+		ctrl := gomock.NewController(t)
+		mockLogger := logmocks.NewMockLogger(ctrl)
+		mockLogger.EXPECT().Error("Failed to cache not found", gomock.Any()).Times(1)
+		assert.Error(t, cm.SetNotFoundCache(ctx, "user:1", mockLogger))
+	})
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// headerMagic is the first byte of every uncompressed value written by RedisCacheManager.
+// It is followed by the tag of the codec used to encode the payload. Values
+// written before codecs were introduced carry no header and are plain JSON,
+// which never starts with a NUL, 0x01 or 0x02 byte.
+const headerMagic byte = 0x00
+
+const (
+	// JSONTag identifies values encoded with JSONCodec.
+	JSONTag byte = 'j'
+
+	// ProtoTag identifies values encoded with ProtoCodec.
+	ProtoTag byte = 'p'
+
+	// GobTag identifies values encoded with GobCodec.
+	GobTag byte = 'g'
+
+	// tombstoneTag is the tag reserved for the tombstones written by SetNotFound.
+	tombstoneTag byte = 'N'
+)
+
+// Codec defines how values are serialized before being stored in the cache.
+//
+// Every stored value is prefixed with a small header carrying the codec tag, so a
+// RedisCacheManager can read values written with any registered codec. This allows
+// a codec change to be rolled out without invalidating the whole cache. The tag 'N'
+// is reserved for tombstones, and custom codecs must not use it nor the tag of a
+// built-in codec; WithCodec panics if they do.
+type Codec interface {
+
+	// Marshal encodes a value into bytes.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes bytes into the value pointed to by v.
+	Unmarshal(data []byte, v interface{}) error
+
+	// Tag returns the byte identifying this codec in the header of stored values.
+	Tag() byte
+}
+
+// JSONCodec encodes values with encoding/json. It is the default codec.
+type JSONCodec struct{}
+
+// Marshal encodes a value as JSON.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data into the value pointed to by v.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Tag returns JSONTag.
+func (JSONCodec) Tag() byte {
+	return JSONTag
+}
+
+// ProtoCodec encodes proto.Message values with the protobuf wire format.
+//
+// Values passed to Marshal must implement proto.Message. Unmarshal accepts either
+// a proto.Message or a pointer to a proto.Message pointer (as used by Typed), in
+// which case the message is allocated.
+type ProtoCodec struct{}
+
+// Marshal encodes a proto.Message using the protobuf wire format.
+func (ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cache: proto codec cannot marshal %T: not a proto.Message", v)
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal decodes protobuf data into the message pointed to by v.
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, msg)
+	}
+
+	// Handle **Message destinations by allocating the message and storing its pointer.
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Ptr {
+		elem := reflect.New(rv.Elem().Type().Elem())
+		if msg, ok := elem.Interface().(proto.Message); ok {
+			if err := proto.Unmarshal(data, msg); err != nil {
+				return err
+			}
+			rv.Elem().Set(elem)
+			return nil
+		}
+	}
+	return fmt.Errorf("cache: proto codec cannot unmarshal into %T: not a proto.Message", v)
+}
+
+// Tag returns ProtoTag.
+func (ProtoCodec) Tag() byte {
+	return ProtoTag
+}
+
+// GobCodec encodes values with encoding/gob.
+type GobCodec struct{}
+
+// Marshal encodes a value with encoding/gob.
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob data into the value pointed to by v.
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Tag returns GobTag.
+func (GobCodec) Tag() byte {
+	return GobTag
+}
+
+// checkCodecTag panics if the codec uses the tag reserved for tombstones, or the tag of a
+// built-in codec it is not, since its values could not be told apart from theirs.
+func checkCodecTag(codec Codec) {
+	tag := codec.Tag()
+	if tag == tombstoneTag {
+		panic(fmt.Sprintf("cache: codec tag %q is reserved for tombstones", tag))
+	}
+	if builtin, ok := builtinCodecs()[tag]; ok && reflect.TypeOf(builtin) != reflect.TypeOf(codec) {
+		panic(fmt.Sprintf("cache: codec tag %q is used by the built-in %T", tag, builtin))
+	}
+}
+
+// builtinCodecs returns the codecs every RedisCacheManager can decode.
+func builtinCodecs() map[byte]Codec {
+	return map[byte]Codec{
+		JSONTag:  JSONCodec{},
+		ProtoTag: ProtoCodec{},
+		GobTag:   GobCodec{},
+	}
+}
+
+// encode serializes a value with the configured codec and prefixes it with the header.
+// Payloads reaching the compression threshold are compressed when compression is enabled
+// and it actually saves space. It also returns the size of the uncompressed payload.
+func (cm *RedisCacheManager) encode(value interface{}) ([]byte, int, error) {
+	if entry, ok := value.(softEntry); ok {
+		return cm.encodeSoft(entry)
+	}
+
+	payload, err := cm.codec.Marshal(value)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if cm.compression != CompressionNone && len(payload) >= cm.compressionThreshold {
+		compressed, err := cm.compression.compress(payload)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(compressed) < len(payload) {
+			data := make([]byte, 0, len(compressed)+3)
+			data = append(data, compressedMagic, byte(cm.compression), cm.codec.Tag())
+			return append(data, compressed...), len(payload), nil
+		}
+	}
+
+	data := make([]byte, 0, len(payload)+2)
+	data = append(data, headerMagic, cm.codec.Tag())
+	return append(data, payload...), len(payload), nil
+}
+
+// decode deserializes a stored value into dest using the codec named in its header,
+// decompressing it first if needed. Values without a header predate codecs and are
+// decoded as JSON.
+func (cm *RedisCacheManager) decode(data []byte, dest interface{}) error {
+	if len(data) >= softHeaderSize && data[0] == softMagic {
+		return cm.decodeSoft(data, dest)
+	}
+	if entry, ok := dest.(*softEntry); ok {
+		dest = entry.Value
+	}
+
+	var tag byte
+	var payload []byte
+	switch {
+	case len(data) >= 3 && data[0] == compressedMagic:
+		decompressed, err := Compression(data[1]).decompress(data[3:])
+		if err != nil {
+			return err
+		}
+		tag, payload = data[2], decompressed
+	case len(data) >= 2 && data[0] == headerMagic:
+		tag, payload = data[1], data[2:]
+	default:
+		return JSONCodec{}.Unmarshal(data, dest)
+	}
+
+	codec, ok := cm.codecs[tag]
+	if !ok {
+		return fmt.Errorf("cache: unknown codec tag %q", tag)
+	}
+	return codec.Unmarshal(payload, dest)
+}
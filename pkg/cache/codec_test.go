@@ -0,0 +1,139 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newManager(server *miniredis.Miniredis, opts ...cache.Option) *cache.RedisCacheManager {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	return cache.New(client, zap.NewNop(), opts...)
+}
+
+func TestRedisCacheManager_Codecs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		codec     cache.Codec
+		expectTag byte
+	}{
+		{name: "JSON", codec: cache.JSONCodec{}, expectTag: cache.JSONTag},
+		{name: "Gob", codec: cache.GobCodec{}, expectTag: cache.GobTag},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			cm := newManager(server, cache.WithCodec(tc.codec))
+			ctx := context.Background()
+
+			cm.Set(ctx, "user:1", user{ID: "1", Name: "Ada"}, time.Minute, time.Second)
+
+			raw, err := server.Get("user:1")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{0x00, tc.expectTag}, []byte(raw[:2]))
+
+			var result user
+			assert.NoError(t, cm.Get(ctx, "user:1", &result, time.Second))
+			assert.Equal(t, user{ID: "1", Name: "Ada"}, result)
+		})
+	}
+}
+
+func TestRedisCacheManager_ProtoCodec(t *testing.T) {
+	server := miniredis.RunT(t)
+	cm := newManager(server, cache.WithCodec(cache.ProtoCodec{}))
+	ctx := context.Background()
+
+	cm.Set(ctx, "greeting", wrapperspb.String("hello"), time.Minute, time.Second)
+
+	t.Run("Message destination", func(t *testing.T) {
+		result := &wrapperspb.StringValue{}
+		assert.NoError(t, cm.Get(ctx, "greeting", result, time.Second))
+		assert.Equal(t, "hello", result.GetValue())
+	})
+
+	t.Run("Typed pointer destination", func(t *testing.T) {
+		result, found, err := cache.NewTyped[*wrapperspb.StringValue](cm).Get(ctx, "greeting", time.Second)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.True(t, proto.Equal(wrapperspb.String("hello"), result))
+	})
+
+	t.Run("Non-message value is rejected", func(t *testing.T) {
+		cm.Set(ctx, "plain", "hello", time.Minute, time.Second)
+		assert.False(t, server.Exists("plain"))
+	})
+}
+
+func TestRedisCacheManager_CodecRollout(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	t.Run("Legacy headerless JSON is readable", func(t *testing.T) {
+		assert.NoError(t, server.Set("legacy", `{"id":"1","name":"Ada"}`))
+
+		var result user
+		assert.NoError(t, newManager(server).Get(ctx, "legacy", &result, time.Second))
+		assert.Equal(t, user{ID: "1", Name: "Ada"}, result)
+	})
+
+	t.Run("Value written with another codec is readable", func(t *testing.T) {
+		newManager(server, cache.WithCodec(cache.GobCodec{})).Set(ctx, "user:1", user{ID: "1"}, time.Minute, time.Second)
+
+		var result user
+		assert.NoError(t, newManager(server).Get(ctx, "user:1", &result, time.Second))
+		assert.Equal(t, user{ID: "1"}, result)
+	})
+
+	t.Run("Unknown codec tag is an error", func(t *testing.T) {
+		assert.NoError(t, server.Set("unknown", "\x00zpayload"))
+
+		var result user
+		assert.Error(t, newManager(server).Get(ctx, "unknown", &result, time.Second))
+	})
+}
+
+// taggedCodec is a JSON codec identified by an arbitrary tag.
+type taggedCodec struct {
+	cache.JSONCodec
+	tag byte
+}
+
+func (c taggedCodec) Tag() byte {
+	return c.tag
+}
+
+func TestWithCodec_ReservedTags(t *testing.T) {
+	testCases := []struct {
+		name        string
+		codec       cache.Codec
+		expectPanic bool
+	}{
+		{name: "Tombstone tag", codec: taggedCodec{tag: 'N'}, expectPanic: true},
+		{name: "Built-in JSON tag", codec: taggedCodec{tag: cache.JSONTag}, expectPanic: true},
+		{name: "Built-in Gob tag", codec: taggedCodec{tag: cache.GobTag}, expectPanic: true},
+		{name: "Built-in codec", codec: cache.ProtoCodec{}},
+		{name: "Custom tag", codec: taggedCodec{tag: 'x'}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			option := func() { cache.WithCodec(tc.codec) }
+			if tc.expectPanic {
+				assert.Panics(t, option)
+			} else {
+				assert.NotPanics(t, option)
+			}
+		})
+	}
+}
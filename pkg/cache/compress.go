@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// compressedMagic is the first byte of compressed values. It is followed by the
+// compression algorithm and the codec tag, then by the compressed payload.
+const compressedMagic byte = 0x01
+
+// DefaultCompressionThreshold is the payload size, in bytes, from which values are
+// compressed when compression is enabled without an explicit threshold.
+const DefaultCompressionThreshold = 1024
+
+// Compression identifies the algorithm used to compress large cached values.
+type Compression byte
+
+const (
+	// CompressionNone disables compression.
+	CompressionNone Compression = 0
+
+	// CompressionGzip compresses values with gzip at the default level.
+	// It gives the best ratio of the built-in algorithms.
+	CompressionGzip Compression = 'z'
+
+	// CompressionDeflate compresses values with raw deflate at the fastest level.
+	// It trades some ratio for much lower CPU cost than gzip.
+	CompressionDeflate Compression = 'd'
+)
+
+// String returns the name of the compression algorithm.
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionDeflate:
+		return "deflate"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(c))
+	}
+}
+
+// WithCompression enables compression of values whose encoded size reaches the threshold.
+// Smaller values are stored uncompressed, and compressed values are decompressed
+// transparently by Get whatever the configuration of the reading instance.
+//
+// Parameters:
+// - compression (Compression): The algorithm used to compress large values.
+// - threshold (int): The minimum encoded size in bytes to compress; DefaultCompressionThreshold if <= 0.
+//
+// Returns:
+// - Option: An option to pass to New.
+func WithCompression(compression Compression, threshold int) Option {
+	return func(cm *RedisCacheManager) {
+		if threshold <= 0 {
+			threshold = DefaultCompressionThreshold
+		}
+		cm.compression = compression
+		cm.compressionThreshold = threshold
+	}
+}
+
+// compress compresses data with the algorithm.
+func (c Compression) compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionDeflate:
+		fw, err := flate.NewWriter(&buf, flate.BestSpeed)
+		if err != nil {
+			return nil, err
+		}
+		w = fw
+	default:
+		return nil, fmt.Errorf("cache: unsupported compression %s", c)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress decompresses data compressed with the algorithm.
+func (c Compression) decompress(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	switch c {
+	case CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		r = gr
+	case CompressionDeflate:
+		r = flate.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("cache: unsupported compression %s", c)
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,50 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCacheManager_Compression(t *testing.T) {
+	large := user{ID: "1", Name: strings.Repeat("Ada Lovelace ", 500)}
+	small := user{ID: "2", Name: "Ada"}
+
+	testCases := []struct {
+		name        string
+		compression cache.Compression
+		value       user
+		expectFirst byte
+	}{
+		{name: "Gzip - Large value compressed", compression: cache.CompressionGzip, value: large, expectFirst: 0x01},
+		{name: "Deflate - Large value compressed", compression: cache.CompressionDeflate, value: large, expectFirst: 0x01},
+		{name: "Gzip - Small value stored as is", compression: cache.CompressionGzip, value: small, expectFirst: 0x00},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			ctx := context.Background()
+			writer := newManager(server, cache.WithCompression(tc.compression, 1024))
+
+			writer.Set(ctx, "user", tc.value, time.Minute, time.Second)
+
+			raw, err := server.Get("user")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectFirst, raw[0])
+
+			// Any instance can read the value, whatever its own compression settings.
+			for _, reader := range []*cache.RedisCacheManager{writer, newManager(server)} {
+				var result user
+				assert.NoError(t, reader.Get(ctx, "user", &result, time.Second))
+				assert.Equal(t, tc.value, result)
+			}
+		})
+	}
+}
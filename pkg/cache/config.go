@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Mode selects the Redis deployment topology a client connects to.
+type Mode string
+
+const (
+	// ModeStandalone connects to a single Redis server.
+	ModeStandalone Mode = "standalone"
+
+	// ModeSentinel connects to the master of a Sentinel-monitored group, following failovers.
+	ModeSentinel Mode = "sentinel"
+
+	// ModeCluster connects to a Redis Cluster.
+	ModeCluster Mode = "cluster"
+)
+
+// Config contains the Redis connection information.
+//
+// Zero values leave the go-redis defaults in place, except for Mode, which is
+// inferred when empty: sentinel if MasterName is set, cluster if several
+// addresses are given, standalone otherwise.
+type Config struct {
+	Mode       Mode     // Deployment topology; inferred if empty
+	Addrs      []string // host:port of the server, the sentinels, or the cluster seed nodes
+	MasterName string   // Name of the master monitored by the sentinels (sentinel mode)
+	DB         int      // Database number (standalone and sentinel modes)
+
+	Username         string // ACL username
+	Password         string // ACL password, or the requirepass password if Username is empty
+	SentinelUsername string // ACL username for the sentinels (sentinel mode)
+	SentinelPassword string // Password for the sentinels (sentinel mode)
+
+	TLSEnabled            bool   // Connect over TLS
+	TLSCAFile             string // PEM file of the CA certificates to trust; system roots if empty
+	TLSCertFile           string // PEM file of the client certificate, for mutual TLS
+	TLSKeyFile            string // PEM file of the client key, for mutual TLS
+	TLSServerName         string // Server name to verify; taken from the address if empty
+	TLSInsecureSkipVerify bool   // Skip server certificate verification; for testing only
+
+	PoolSize        int           // Maximum number of connections per node
+	MinIdleConns    int           // Minimum number of idle connections kept open per node
+	PoolTimeout     time.Duration // Maximum time to wait for a free connection
+	ConnMaxIdleTime time.Duration // Maximum time a connection may stay idle before being closed
+
+	DialTimeout  time.Duration // Timeout for establishing new connections
+	ReadTimeout  time.Duration // Timeout for socket reads
+	WriteTimeout time.Duration // Timeout for socket writes
+
+	MaxRetries      int           // Maximum number of retries of a failed command; -1 disables retries
+	MinRetryBackoff time.Duration // Minimum backoff between retries
+	MaxRetryBackoff time.Duration // Maximum backoff between retries
+}
+
+// NewRedisClient creates a new Redis client from the configuration.
+// It builds a standalone, sentinel or cluster client depending on the mode, pings
+// the server to ensure it's reachable, and returns the client. It uses a timeout for
+// the initial ping. If the configuration is invalid the error is logged and returned;
+// if the server cannot be reached, the function logs a fatal error and returns an error.
+//
+// Parameters:
+// - config (Config): The Redis configuration struct.
+// - logger (logger.Logger): The logger for recording information and errors.
+//
+// Returns:
+// - redis.UniversalClient: The Redis client, usable with New, NewInvalidationBus and the health check.
+// - error: An error if the configuration is invalid or the ping fails.
+func NewRedisClient(config Config, logger logger.Logger) (redis.UniversalClient, error) {
+	client, err := buildClient(config)
+	if err != nil {
+		logger.Error("Invalid Redis configuration", zap.Error(err))
+		return nil, err
+	}
+
+	// Create a context with a timeout to manage long connections
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Test the connection
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		logger.Fatal("Error pinging Redis", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Successfully connected to Redis",
+		zap.String("mode", string(config.mode())),
+		zap.Strings("addrs", config.Addrs),
+		zap.Bool("tls", config.TLSEnabled))
+	return client, nil
+}
+
+// mode returns the configured mode, inferring it when empty.
+func (c Config) mode() Mode {
+	switch {
+	case c.Mode != "":
+		return c.Mode
+	case c.MasterName != "":
+		return ModeSentinel
+	case len(c.Addrs) > 1:
+		return ModeCluster
+	default:
+		return ModeStandalone
+	}
+}
+
+// buildClient validates the configuration and creates the client for its mode, without connecting.
+func buildClient(config Config) (redis.UniversalClient, error) {
+	if len(config.Addrs) == 0 {
+		return nil, errors.New("cache: no Redis address configured")
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            config.Addrs,
+		MasterName:       config.MasterName,
+		DB:               config.DB,
+		Username:         config.Username,
+		Password:         config.Password,
+		SentinelUsername: config.SentinelUsername,
+		SentinelPassword: config.SentinelPassword,
+		TLSConfig:        tlsConfig,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		PoolTimeout:      config.PoolTimeout,
+		ConnMaxIdleTime:  config.ConnMaxIdleTime,
+		DialTimeout:      config.DialTimeout,
+		ReadTimeout:      config.ReadTimeout,
+		WriteTimeout:     config.WriteTimeout,
+		MaxRetries:       config.MaxRetries,
+		MinRetryBackoff:  config.MinRetryBackoff,
+		MaxRetryBackoff:  config.MaxRetryBackoff,
+	}
+
+	switch mode := config.mode(); mode {
+	case ModeStandalone:
+		if len(config.Addrs) > 1 {
+			return nil, errors.New("cache: standalone mode takes a single Redis address")
+		}
+		return redis.NewClient(opts.Simple()), nil
+	case ModeSentinel:
+		if config.MasterName == "" {
+			return nil, errors.New("cache: sentinel mode requires a master name")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case ModeCluster:
+		if config.DB != 0 {
+			return nil, errors.New("cache: cluster mode only supports database 0")
+		}
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("cache: unknown Redis mode %q", mode)
+	}
+}
+
+// tlsConfig builds the TLS configuration, or returns nil if TLS is disabled.
+func (c Config) tlsConfig() (*tls.Config, error) {
+	if !c.TLSEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cache: reading Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("cache: no certificate found in Redis CA file %q", c.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cache: loading Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
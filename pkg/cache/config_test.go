@@ -0,0 +1,180 @@
+package cache_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+	mocklogger "github.com/kmmania/er_commonlib/pkg/mocks/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("cache", "secret")
+
+	testCases := []struct {
+		name       string
+		config     cache.Config
+		expectType interface{}
+	}{
+		{
+			name:       "Standalone",
+			config:     cache.Config{Addrs: []string{server.Addr()}, Username: "cache", Password: "secret", PoolSize: 4},
+			expectType: &redis.Client{},
+		},
+		{
+			name: "Cluster",
+			config: cache.Config{
+				Mode:     cache.ModeCluster,
+				Addrs:    []string{server.Addr()},
+				Username: "cache",
+				Password: "secret",
+			},
+			expectType: &redis.ClusterClient{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := cache.NewRedisClient(tc.config, zap.NewNop())
+			require.NoError(t, err)
+			defer client.Close()
+			assert.IsType(t, tc.expectType, client)
+
+			cm := cache.New(client, zap.NewNop())
+			cm.Set(context.Background(), "user:1", user{ID: "1"}, time.Minute, time.Second)
+			var got user
+			assert.NoError(t, cm.Get(context.Background(), "user:1", &got, time.Second))
+			assert.Equal(t, user{ID: "1"}, got)
+
+			assert.NoError(t, cm.InvalidatePrefix(context.Background(), "user:", zap.NewNop()))
+			assert.False(t, server.Exists("user:1"))
+		})
+	}
+}
+
+func TestNewRedisClient_TLS(t *testing.T) {
+	serverTLS, caFile := newTLSConfig(t)
+	server := miniredis.NewMiniRedis()
+	require.NoError(t, server.StartTLS(serverTLS))
+	t.Cleanup(server.Close)
+
+	client, err := cache.NewRedisClient(cache.Config{
+		Addrs:         []string{server.Addr()},
+		TLSEnabled:    true,
+		TLSCAFile:     caFile,
+		TLSServerName: "localhost",
+	}, zap.NewNop())
+	require.NoError(t, err)
+	defer client.Close()
+	assert.NoError(t, client.Ping(context.Background()).Err())
+}
+
+func TestNewRedisClient_Errors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    cache.Config
+		expectLog func(log *mocklogger.MockLogger)
+	}{
+		{
+			name:   "No Address",
+			config: cache.Config{},
+		},
+		{
+			name:   "Unknown Mode",
+			config: cache.Config{Mode: "replicated", Addrs: []string{"localhost:6379"}},
+		},
+		{
+			name:   "Standalone With Several Addresses",
+			config: cache.Config{Mode: cache.ModeStandalone, Addrs: []string{"a:6379", "b:6379"}},
+		},
+		{
+			name:   "Sentinel Without Master Name",
+			config: cache.Config{Mode: cache.ModeSentinel, Addrs: []string{"localhost:26379"}},
+		},
+		{
+			name:   "Cluster With Database",
+			config: cache.Config{Addrs: []string{"a:6379", "b:6379"}, DB: 1},
+		},
+		{
+			name:   "Missing CA File",
+			config: cache.Config{Addrs: []string{"localhost:6379"}, TLSEnabled: true, TLSCAFile: "/does/not/exist"},
+		},
+		{
+			name: "Unreachable",
+			config: cache.Config{
+				Addrs:       []string{"127.0.0.1:1"},
+				DialTimeout: 100 * time.Millisecond,
+				MaxRetries:  -1,
+			},
+			expectLog: func(log *mocklogger.MockLogger) {
+				log.EXPECT().Fatal("Error pinging Redis", gomock.Any()).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			log := mocklogger.NewMockLogger(ctrl)
+			if tc.expectLog != nil {
+				tc.expectLog(log)
+			} else {
+				log.EXPECT().Error("Invalid Redis configuration", gomock.Any()).Times(1)
+			}
+
+			client, err := cache.NewRedisClient(tc.config, log)
+			assert.Error(t, err)
+			assert.Nil(t, client)
+		})
+	}
+}
+
+// newTLSConfig returns a server TLS configuration for localhost and the path of a PEM file
+// holding its self-signed certificate, to be trusted by clients.
+func newTLSConfig(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}, caFile
+}
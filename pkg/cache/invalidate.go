@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	// tagKeyPrefix is the prefix of the Redis sets holding the keys associated with a tag.
+	tagKeyPrefix = "cache:tag:"
+
+	// scanBatchSize is the COUNT hint passed to SCAN, and the number of keys deleted per round trip.
+	scanBatchSize = 500
+
+	// SlidingTagLifetimeFactor is how many times its TTL a sliding key is kept in its tag
+	// sets after it was last written by SetCache.
+	SlidingTagLifetimeFactor = 10
+)
+
+// addToTagScript adds a key to a tag set and extends the set's expiry so that it never
+// expires before the key it references. A set left without expiry gets one.
+var addToTagScript = redis.NewScript(`
+local pttl = redis.call('PTTL', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if pttl < ttl then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
+// tagKey returns the Redis key of the set holding the keys associated with the tag.
+func tagKey(tag string) string {
+	return tagKeyPrefix + tag
+}
+
+// queueTags queues, on the pipeline, the commands associating the key with each tag.
+// Tag sets hold keys without the global prefix, the tag sets themselves being prefixed.
+// A sliding key may outlive any TTL given to its tag sets, as reads extend it, so they are
+// kept for SlidingTagLifetimeFactor times its TTL: long enough for keys rewritten or read
+// in bursts, while the sets of tags never invalidated still expire.
+// The script is sent with EVAL rather than EVALSHA because a NOSCRIPT error cannot be
+// recovered from inside a pipeline.
+func (cm *RedisCacheManager) queueTags(
+	ctx context.Context,
+	pipe redis.Pipeliner,
+	key string,
+	ttl time.Duration,
+	tags []string,
+) {
+	setTTL := ttl.Milliseconds()
+	if cm.sliding(key) {
+		setTTL *= SlidingTagLifetimeFactor
+	}
+	for _, tag := range tags {
+		addToTagScript.Eval(ctx, pipe, []string{cm.key(tagKey(tag))}, key, setTTL)
+	}
+}
+
+// InvalidateTag removes every cached entry associated with the tag by SetCache, then the tag itself.
+// It logs the operation and returns an error if the invalidation fails.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - tag (string): The tag whose cached entries should be invalidated.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the cache invalidation fails, or nil if successful.
+func (cm *RedisCacheManager) InvalidateTag(ctx context.Context, tag string, logger logger.Logger) error {
+	keys, err := cm.invalidateTag(ctx, tag)
+	if err != nil {
+		logger.Error("Failed to invalidate cache tag", zap.String("tag", tag), zap.Error(err))
+		return err
+	}
+	logger.Info("Cache tag invalidated successfully", zap.String("tag", tag), zap.Int("keys", len(keys)))
+	return nil
+}
+
+// InvalidatePrefix removes every cached entry whose key starts with the prefix.
+// Keys are enumerated incrementally with SCAN, never with KEYS, so the operation does
+// not block the Redis server even on large databases.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - prefix (string): The key prefix of the cached entries to invalidate.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the cache invalidation fails, or nil if successful.
+func (cm *RedisCacheManager) InvalidatePrefix(ctx context.Context, prefix string, logger logger.Logger) error {
+	deleted, err := cm.invalidatePrefix(ctx, prefix)
+	if err != nil {
+		logger.Error("Failed to invalidate cache prefix", zap.String("prefix", prefix), zap.Error(err))
+		return err
+	}
+	logger.Info("Cache prefix invalidated successfully", zap.String("prefix", prefix), zap.Int("keys", deleted))
+	return nil
+}
+
+// invalidateTag deletes the keys associated with the tag and the tag set, returning the deleted keys.
+func (cm *RedisCacheManager) invalidateTag(ctx context.Context, tag string) ([]string, error) {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, CachedTimeout)
+	keys, err := cm.client.SMembers(ctxWithTimeout, cm.key(tagKey(tag))).Result()
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(keys); start += scanBatchSize {
+		end := min(start+scanBatchSize, len(keys))
+		if err := cm.deleteKeys(ctx, keys[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	// The tag set is removed last so that a failed invalidation can be retried.
+	if err := cm.deleteKeys(ctx, []string{tagKey(tag)}); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// invalidatePrefix enumerates the keys matching the prefix, then deletes them in batches,
+// returning how many were deleted. Deletion starts once the scan is complete so that the
+// keyspace does not change under the cursor.
+func (cm *RedisCacheManager) invalidatePrefix(ctx context.Context, prefix string) (int, error) {
+	keys, err := cm.scanKeys(ctx, escapeGlob(cm.key(prefix))+"*")
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, cm.keyPrefix)
+	}
+
+	for start := 0; start < len(keys); start += scanBatchSize {
+		end := min(start+scanBatchSize, len(keys))
+		if err := cm.deleteKeys(ctx, keys[start:end]); err != nil {
+			return start, err
+		}
+	}
+	return len(keys), nil
+}
+
+// scanKeys returns every key matching the pattern. In cluster mode each master holds
+// part of the keyspace, so all of them are scanned.
+func (cm *RedisCacheManager) scanKeys(ctx context.Context, match string) ([]string, error) {
+	cluster, ok := cm.client.(*redis.ClusterClient)
+	if !ok {
+		return scanNode(ctx, cm.client, match)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		nodeKeys, err := scanNode(ctx, node, match)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, nodeKeys...)
+		mu.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
+// scanNode returns every key matching the pattern on a single node, following the SCAN cursor.
+func scanNode(ctx context.Context, client redis.Cmdable, match string) ([]string, error) {
+	var cursor uint64
+	var keys []string
+	for {
+		ctxWithTimeout, cancel := context.WithTimeout(ctx, CachedTimeout)
+		page, next, err := client.Scan(ctxWithTimeout, cursor, match, scanBatchSize).Result()
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+
+		cursor = next
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// deleteKeys deletes the keys, given without the global prefix, in a single pipelined round trip, one DEL per key so that
+// keys hashing to different cluster slots can be deleted together.
+func (cm *RedisCacheManager) deleteKeys(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, CachedTimeout)
+	defer cancel()
+
+	pipe := cm.client.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctxWithTimeout, cm.key(key))
+	}
+	_, err := pipe.Exec(ctxWithTimeout)
+	return err
+}
+
+// escapeGlob escapes the characters having a special meaning in Redis glob-style patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRedisCacheManager_InvalidateTag(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	cm.SetCache(ctx, "user:42", user{ID: "42"}, log, "user:42")
+	cm.SetCache(ctx, "users:page:1", []user{{ID: "42"}}, log, "user:42", "users")
+	cm.SetCache(ctx, "users:page:2", []user{{ID: "43"}}, log, "users")
+	cm.SetCache(ctx, "user:43", user{ID: "43"}, log)
+
+	assert.NoError(t, cm.InvalidateTag(ctx, "user:42", log))
+
+	assert.False(t, server.Exists("user:42"))
+	assert.False(t, server.Exists("users:page:1"))
+	assert.True(t, server.Exists("users:page:2"))
+	assert.True(t, server.Exists("user:43"))
+
+	// Invalidating an unknown tag is a no-op.
+	assert.NoError(t, cm.InvalidateTag(ctx, "unknown", log))
+}
+
+func TestRedisCacheManager_InvalidateTag_ExpiryFollowsLongestKey(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	cm.SetCache(ctx, "user:42", user{ID: "42"}, log, "user:42")
+	cm.Set(ctx, "user:42:short", user{ID: "42"}, time.Minute, time.Second)
+
+	assert.Equal(t, time.Hour, server.TTL("cache:tag:user:42"))
+}
+
+func TestRedisCacheManager_InvalidatePrefix(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	// More keys than a single SCAN page to exercise the cursor loop.
+	for i := 0; i < 1200; i++ {
+		cm.Set(ctx, fmt.Sprintf("search:%d", i), i, time.Minute, time.Second)
+	}
+	cm.Set(ctx, "search*literal", 1, time.Minute, time.Second)
+	cm.Set(ctx, "searchable", 1, time.Minute, time.Second)
+	cm.Set(ctx, "user:1", 1, time.Minute, time.Second)
+
+	assert.NoError(t, cm.InvalidatePrefix(ctx, "search:", log))
+	assert.Equal(t, []string{"search*literal", "searchable", "user:1"}, server.Keys())
+
+	// Glob characters in the prefix are matched literally.
+	assert.NoError(t, cm.InvalidatePrefix(ctx, "search*", log))
+	assert.Equal(t, []string{"searchable", "user:1"}, server.Keys())
+}
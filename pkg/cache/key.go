@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KeySeparator separates the segments of keys built by KeyBuilder.
+	KeySeparator = ":"
+
+	// DefaultMaxKeyLength is the default maximum length of keys built by KeyBuilder.
+	// Longer keys have their parts replaced by a hash.
+	DefaultMaxKeyLength = 200
+)
+
+// KeyBuilder composes cache keys of the form "namespace:entity:vN:part1:part2".
+//
+// The namespace identifies the service owning the key, the entity the kind of cached value,
+// and the schema version the layout of the cached struct: bumping the version when the
+// struct changes makes every service read fresh keys instead of decoding stale ones.
+// Parts are escaped so that a part containing the separator can never make two different
+// lists of parts produce the same key.
+//
+// A KeyBuilder is an immutable value and is safe for concurrent use.
+type KeyBuilder struct {
+	// prefix is the escaped "namespace:entity:vN:" prefix shared by every key.
+	prefix string
+	// maxLength is the length above which the parts are hashed.
+	maxLength int
+}
+
+// NewKeyBuilder creates and returns a new KeyBuilder.
+//
+// Parameters:
+// - namespace (string): The service namespace, e.g. "billing".
+// - entity (string): The entity type, e.g. "invoice".
+// - version (int): The schema version of the cached value.
+//
+// Returns:
+// - KeyBuilder: A KeyBuilder limiting keys to DefaultMaxKeyLength.
+func NewKeyBuilder(namespace, entity string, version int) KeyBuilder {
+	prefix := escapeKeyPart(namespace) + KeySeparator +
+		escapeKeyPart(entity) + KeySeparator +
+		"v" + strconv.Itoa(version) + KeySeparator
+	return KeyBuilder{prefix: prefix, maxLength: DefaultMaxKeyLength}
+}
+
+// WithMaxLength returns a copy of the builder limiting keys to maxLength bytes.
+// A non-positive maxLength disables the limit.
+//
+// Parameters:
+// - maxLength (int): The maximum key length in bytes.
+//
+// Returns:
+// - KeyBuilder: The configured copy of the builder.
+func (b KeyBuilder) WithMaxLength(maxLength int) KeyBuilder {
+	b.maxLength = maxLength
+	return b
+}
+
+// Key returns the key identifying the given parts.
+// If the key would exceed the maximum length, the parts are replaced by the hex SHA-256
+// of their escaped form, prefixed with "#", so that the key keeps its namespace, entity and
+// version and can still be invalidated with Prefix.
+//
+// Parameters:
+// - parts (...string): The parts identifying the value, e.g. an ID or query parameters.
+//
+// Returns:
+// - string: The cache key.
+func (b KeyBuilder) Key(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = escapeKeyPart(part)
+	}
+	joined := strings.Join(escaped, KeySeparator)
+
+	if b.maxLength > 0 && len(b.prefix)+len(joined) > b.maxLength {
+		sum := sha256.Sum256([]byte(joined))
+		return b.prefix + "#" + hex.EncodeToString(sum[:])
+	}
+	return b.prefix + joined
+}
+
+// Prefix returns the prefix shared by every key of the builder, for use with InvalidatePrefix.
+//
+// Returns:
+// - string: The "namespace:entity:vN:" prefix.
+func (b KeyBuilder) Prefix() string {
+	return b.prefix
+}
+
+// escapeKeyPart percent-encodes the separator, the escape character itself, the hash
+// marker, and control and space characters, leaving every other byte unchanged. An empty
+// part is encoded as a lone "%", which no other part encodes to, so that a single empty
+// part is told apart from no parts at all.
+func escapeKeyPart(part string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	if part == "" {
+		return "%"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		switch {
+		case c == KeySeparator[0], c == '%', c == '#', c <= ' ', c == 0x7f:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
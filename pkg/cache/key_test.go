@@ -0,0 +1,109 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestKeyBuilder_Key(t *testing.T) {
+	invoices := cache.NewKeyBuilder("billing", "invoice", 2)
+
+	testCases := []struct {
+		name     string
+		builder  cache.KeyBuilder
+		parts    []string
+		expected string
+	}{
+		{name: "Single Part", builder: invoices, parts: []string{"42"}, expected: "billing:invoice:v2:42"},
+		{name: "Several Parts", builder: invoices, parts: []string{"42", "lines"}, expected: "billing:invoice:v2:42:lines"},
+		{name: "No Parts", builder: invoices, expected: "billing:invoice:v2:"},
+		{name: "Empty Part", builder: invoices, parts: []string{""}, expected: "billing:invoice:v2:%"},
+		{name: "Empty Parts", builder: invoices, parts: []string{"", ""}, expected: "billing:invoice:v2:%:%"},
+		{name: "Separator Escaped", builder: invoices, parts: []string{"a:b"}, expected: "billing:invoice:v2:a%3Ab"},
+		{
+			name:     "Special Characters Escaped",
+			builder:  invoices,
+			parts:    []string{"50% off", "#1\n"},
+			expected: "billing:invoice:v2:50%25%20off:%231%0A",
+		},
+		{name: "Unicode Kept", builder: invoices, parts: []string{"café"}, expected: "billing:invoice:v2:café"},
+		{
+			name:     "Namespace Escaped",
+			builder:  cache.NewKeyBuilder("bill:ing", "invoice", 1),
+			parts:    []string{"42"},
+			expected: "bill%3Aing:invoice:v1:42",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key := tc.builder.Key(tc.parts...)
+			assert.Equal(t, tc.expected, key)
+			assert.True(t, strings.HasPrefix(key, tc.builder.Prefix()))
+		})
+	}
+}
+
+func TestKeyBuilder_NoCollisions(t *testing.T) {
+	b := cache.NewKeyBuilder("billing", "invoice", 1)
+	assert.NotEqual(t, b.Key("a:b"), b.Key("a", "b"))
+	assert.NotEqual(t, b.Key("a%3Ab"), b.Key("a:b"))
+	assert.NotEqual(t, b.Key(), b.Key(""))
+	assert.NotEqual(t, b.Key(""), b.Key("%"))
+	assert.NotEqual(t, b.Key("a", ""), b.Key("a"))
+	assert.NotEqual(t, cache.NewKeyBuilder("billing", "invoice", 1).Key("42"), cache.NewKeyBuilder("billing", "invoice", 2).Key("42"))
+}
+
+func TestKeyBuilder_MaxLength(t *testing.T) {
+	b := cache.NewKeyBuilder("search", "results", 1).WithMaxLength(64)
+	long := strings.Repeat("q", 100)
+
+	key := b.Key("query", long)
+	assert.LessOrEqual(t, len(key), len(b.Prefix())+65)
+	assert.True(t, strings.HasPrefix(key, "search:results:v1:#"))
+	assert.Equal(t, key, b.Key("query", long), "hashing is deterministic")
+	assert.NotEqual(t, key, b.Key("query", long+"r"))
+
+	// A literal part cannot look like a hash.
+	assert.Equal(t, "search:results:v1:%23abc", b.Key("#abc"))
+
+	assert.Len(t, b.WithMaxLength(0).Key(long), len(b.Prefix())+len(long))
+}
+
+func TestRedisCacheManager_KeyPrefix(t *testing.T) {
+	server := miniredis.RunT(t)
+	billing := newManager(server, cache.WithKeyPrefix("billing:"))
+	shipping := newManager(server, cache.WithKeyPrefix("shipping:"))
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	assert.NoError(t, billing.SetCache(ctx, "user:1", user{ID: "1"}, log, "users"))
+	assert.NoError(t, shipping.SetCache(ctx, "user:1", user{ID: "2"}, log, "users"))
+	assert.NoError(t, billing.SetMany(ctx, map[string]interface{}{"user:2": user{ID: "3"}}, time.Minute))
+	assert.NoError(t, billing.SetNotFound(ctx, "user:3", time.Minute, time.Second))
+	assert.Equal(t, []string{
+		"billing:cache:tag:users", "billing:user:1", "billing:user:2", "billing:user:3",
+		"shipping:cache:tag:users", "shipping:user:1",
+	}, server.Keys())
+
+	var got user
+	assert.NoError(t, billing.Get(ctx, "user:1", &got, time.Second))
+	assert.Equal(t, "1", got.ID)
+	result, err := billing.GetMany(ctx, []string{"user:1", "user:2", "user:3"}, func() interface{} { return new(user) })
+	assert.NoError(t, err)
+	assert.Len(t, result.Hits, 2)
+	assert.Equal(t, []string{"user:3"}, result.NotFound)
+
+	assert.NoError(t, billing.InvalidateTag(ctx, "users", log))
+	assert.NoError(t, billing.InvalidatePrefix(ctx, "user:", log))
+	assert.NoError(t, billing.Delete(ctx, "user:9", time.Second))
+	assert.Equal(t, []string{"shipping:cache:tag:users", "shipping:user:1"}, server.Keys())
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+)
+
+// LegacyRedisCache is the RedisCache contract from before Set and SetCache reported errors.
+// It lets code written against the old signatures, such as hand-written fakes or helpers
+// taking the interface, keep compiling while it is migrated.
+//
+// Deprecated: Use RedisCache, whose Set and SetCache return an error.
+type LegacyRedisCache interface {
+
+	// Get retrieves a value from the cache by its key, applying a timeout.
+	Get(ctx context.Context, key string, dest interface{}, timeout time.Duration) error
+
+	// Set sets a value in the cache with a specified TTL, applying a timeout.
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration, timeout time.Duration)
+
+	// Delete removes a value from the cache by its key, applying a timeout.
+	Delete(ctx context.Context, key string, timeout time.Duration) error
+
+	// GetFromCache retrieves data from the cache if it exists.
+	GetFromCache(ctx context.Context, key string, logger logger.Logger, target interface{}) (bool, error)
+
+	// SetCache stores data in the cache with the specified key.
+	SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger)
+
+	// InvalidateCache removes cached data for a specific key.
+	InvalidateCache(ctx context.Context, key string, logger logger.Logger) error
+}
+
+// legacyCache adapts a RedisCache to LegacyRedisCache by discarding write errors,
+// which the underlying cache has already logged.
+type legacyCache struct {
+	RedisCache
+}
+
+// NewLegacy wraps a RedisCache into the LegacyRedisCache signatures.
+//
+// Parameters:
+// - cache (RedisCache): The cache to adapt, typically a *RedisCacheManager.
+//
+// Returns:
+// - LegacyRedisCache: A cache whose Set and SetCache discard errors as they used to.
+//
+// Deprecated: Use the RedisCache directly and handle the errors of Set and SetCache.
+func NewLegacy(cache RedisCache) LegacyRedisCache {
+	return legacyCache{RedisCache: cache}
+}
+
+// Set sets a value in the cache with a specified TTL, applying a timeout, discarding any error.
+func (l legacyCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, timeout time.Duration) {
+	_ = l.RedisCache.Set(ctx, key, value, ttl, timeout)
+}
+
+// SetCache stores data in the cache with the specified key, discarding any error.
+func (l legacyCache) SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger) {
+	_ = l.RedisCache.SetCache(ctx, key, data, logger)
+}
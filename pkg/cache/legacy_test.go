@@ -0,0 +1,53 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestNewLegacy(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	env.mockCache.EXPECT().
+		Set(gomock.Any(), "user:1", "cached", time.Minute, time.Second).
+		Return(errors.New("redis error"))
+	env.mockCache.EXPECT().
+		SetCache(gomock.Any(), "user:1", "cached", log).
+		Return(errors.New("redis error"))
+	env.mockCache.EXPECT().
+		InvalidateCache(gomock.Any(), "user:1", log).
+		Return(errors.New("redis error"))
+
+	legacy := cache.NewLegacy(env.mockCache)
+	legacy.Set(ctx, "user:1", "cached", time.Minute, time.Second)
+	legacy.SetCache(ctx, "user:1", "cached", log)
+	// Methods whose signature did not change still report errors.
+	assert.Error(t, legacy.InvalidateCache(ctx, "user:1", log))
+}
+
+// oldFake is a fake written against the RedisCache contract from before Set and SetCache
+// reported errors; it must keep satisfying LegacyRedisCache.
+type oldFake struct{}
+
+var _ cache.LegacyRedisCache = oldFake{}
+
+func (oldFake) Get(context.Context, string, interface{}, time.Duration) error          { return nil }
+func (oldFake) Set(context.Context, string, interface{}, time.Duration, time.Duration) {}
+func (oldFake) Delete(context.Context, string, time.Duration) error                    { return nil }
+func (oldFake) GetFromCache(context.Context, string, logger.Logger, interface{}) (bool, error) {
+	return false, nil
+}
+func (oldFake) SetCache(context.Context, string, interface{}, logger.Logger) {}
+func (oldFake) InvalidateCache(context.Context, string, logger.Logger) error { return nil }
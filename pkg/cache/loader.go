@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/repository"
+)
+
+// GetOrLoad returns the value cached under key, loading and caching it on a miss.
+//
+// On a cache miss the loader is invoked and its result is written back to the cache
+// with the given TTL. Concurrent misses for the same key on this Typed instance are
+// collapsed into a single loader call whose result is shared by every waiting caller,
+// which protects the underlying data store from stampedes on cold keys.
+//
+// Negative results are cached too: if the loader returns repository.ErrNotFound, a
+// tombstone is stored for NotFoundLifetime and later calls return repository.ErrNotFound
+// without invoking the loader.
+//
+// Redis calls use CachedTimeout. A failing cache never fails the request: read errors
+// are treated as misses and write errors are only logged by the underlying cache.
+//
+// The loader runs with a context detached from the caller's cancellation, so that
+// one caller giving up does not abort a load that other callers are waiting on; the
+// loader is responsible for applying its own timeouts. Each caller still stops
+// waiting as soon as its own context is done.
+//
+// Options enable stale-while-revalidate and probabilistic early refresh; see
+// WithStaleWhileRevalidate, WithEarlyRefresh and GetOrLoadWithMeta.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key of the cache entry to retrieve or populate.
+//   - ttl (time.Duration): The time-to-live applied when caching a loaded value.
+//   - loader (func(context.Context) (T, error)): The function loading the value on a miss.
+//   - opts (...LoadOption): Optional refresh settings.
+//
+// Returns:
+//   - T: The cached or freshly loaded value.
+//   - error: The loader error, or the context error if ctx is done before the value is available.
+func (t *Typed[T]) GetOrLoad(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	opts ...LoadOption,
+) (T, error) {
+	value, _, err := t.GetOrLoadWithMeta(ctx, key, ttl, loader, opts...)
+	return value, err
+}
+
+// GetOrLoadWithMeta behaves like GetOrLoad and also reports where the value came from.
+//
+// With WithStaleWhileRevalidate or WithEarlyRefresh, loaded values are stored with a
+// soft expiry ttl from now, and kept in the cache until the stale window has elapsed
+// too. A value read past its soft expiry is returned immediately, flagged as stale,
+// while a background refresh replaces it; a fresh value may also be refreshed early.
+// Background refreshes share the in-flight load of the key, so at most one runs per key
+// on this Typed instance, and their errors are not reported to any caller.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key of the cache entry to retrieve or populate.
+//   - ttl (time.Duration): The time after which a loaded value is considered stale.
+//   - loader (func(context.Context) (T, error)): The function loading the value on a miss.
+//   - opts (...LoadOption): Optional refresh settings.
+//
+// Returns:
+//   - T: The cached or freshly loaded value.
+//   - LoadMeta: Whether the value was cached, stale, or is being refreshed.
+//   - error: The loader error, or the context error if ctx is done before the value is available.
+func (t *Typed[T]) GetOrLoadWithMeta(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	opts ...LoadOption,
+) (T, LoadMeta, error) {
+	var zero T
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	load := t.load(ctx, key, ttl, loader, o)
+
+	// Read errors are already logged by the underlying cache; fall through to the loader.
+	var value T
+	entry := softEntry{Value: &value}
+	var err error
+	if o.soft() {
+		err = t.cache.Get(ctx, key, &entry, CachedTimeout)
+	} else {
+		err = t.cache.Get(ctx, key, &value, CachedTimeout)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return zero, LoadMeta{Cached: true}, err
+	}
+	if err == nil {
+		meta := LoadMeta{Cached: true, SoftExpiry: entry.SoftExpiry}
+		if entry.SoftExpiry.IsZero() {
+			return value, meta, nil
+		}
+
+		now := time.Now()
+		switch {
+		case now.Before(entry.SoftExpiry):
+			if o.beta > 0 && shouldRefreshEarly(now, entry.SoftExpiry, entry.Delta, o.beta) {
+				t.group.DoChan(key, load)
+				meta.Refreshing = true
+			}
+			return value, meta, nil
+		case o.staleWindow > 0:
+			t.group.DoChan(key, load)
+			meta.Stale, meta.Refreshing = true, true
+			return value, meta, nil
+		}
+		// Soft-expired without a stale window: load it like a miss.
+	}
+
+	ch := t.group.DoChan(key, load)
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, LoadMeta{}, res.Err
+		}
+		value, _ := res.Val.(T)
+		return value, LoadMeta{}, nil
+	case <-ctx.Done():
+		return zero, LoadMeta{}, ctx.Err()
+	}
+}
+
+// load returns the function loading a value and writing it back to the cache, shared by
+// every caller waiting on the key. The value is stored with a soft expiry if options enable it.
+func (t *Typed[T]) load(
+	ctx context.Context,
+	key string,
+	ttl time.Duration,
+	loader func(ctx context.Context) (T, error),
+	o loadOptions,
+) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		start := time.Now()
+		value, err := loader(loadCtx)
+		if errors.Is(err, repository.ErrNotFound) {
+			// The cache logged any failure; the caller gets the loader's error either way.
+			_ = t.cache.SetNotFound(loadCtx, key, NotFoundLifetime, CachedTimeout)
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if o.soft() {
+			now := time.Now()
+			entry := softEntry{Value: value, SoftExpiry: now.Add(ttl), Delta: now.Sub(start)}
+			_ = t.cache.Set(loadCtx, key, entry, ttl+o.staleWindow, CachedTimeout)
+		} else {
+			_ = t.cache.Set(loadCtx, key, value, ttl, CachedTimeout)
+		}
+		return value, nil
+	}
+}
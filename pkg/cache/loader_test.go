@@ -0,0 +1,192 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+	"github.com/kmmania/er_commonlib/pkg/repository"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTyped_GetOrLoad(t *testing.T) {
+	type testCase struct {
+		name        string
+		setupMock   func(env *testEnv)
+		loader      func(ctx context.Context) (user, error)
+		expectedVal user
+		expectedErr error
+	}
+
+	tests := []testCase{
+		{
+			name: "Hit - Loader Not Called",
+			setupMock: func(env *testEnv) {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+					DoAndReturn(func(ctx context.Context, key string, dest interface{}, timeout time.Duration) error {
+						*(dest.(*user)) = user{ID: "1"}
+						return nil
+					})
+			},
+			loader: func(ctx context.Context) (user, error) {
+				return user{}, errors.New("loader must not be called")
+			},
+			expectedVal: user{ID: "1"},
+		},
+		{
+			name: "Miss - Loaded And Written Back",
+			setupMock: func(env *testEnv) {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+					Return(cache.ErrCacheMiss)
+				env.mockCache.EXPECT().
+					Set(gomock.Any(), "user:1", user{ID: "1"}, time.Minute, cache.CachedTimeout).
+					Times(1)
+			},
+			loader: func(ctx context.Context) (user, error) {
+				return user{ID: "1"}, nil
+			},
+			expectedVal: user{ID: "1"},
+		},
+		{
+			name: "Redis Down - Loader Still Serves",
+			setupMock: func(env *testEnv) {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+					Return(errors.New("connection refused"))
+				env.mockCache.EXPECT().
+					Set(gomock.Any(), "user:1", user{ID: "1"}, time.Minute, cache.CachedTimeout).
+					Times(1)
+			},
+			loader: func(ctx context.Context) (user, error) {
+				return user{ID: "1"}, nil
+			},
+			expectedVal: user{ID: "1"},
+		},
+		{
+			name: "Tombstone - Loader Not Called",
+			setupMock: func(env *testEnv) {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+					Return(repository.ErrNotFound)
+			},
+			loader: func(ctx context.Context) (user, error) {
+				return user{}, errors.New("loader must not be called")
+			},
+			expectedErr: repository.ErrNotFound,
+		},
+		{
+			name: "Loader Not Found - Tombstone Cached",
+			setupMock: func(env *testEnv) {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+					Return(cache.ErrCacheMiss)
+				env.mockCache.EXPECT().
+					SetNotFound(gomock.Any(), "user:1", cache.NotFoundLifetime, cache.CachedTimeout).
+					Return(nil).
+					Times(1)
+			},
+			loader: func(ctx context.Context) (user, error) {
+				return user{}, repository.ErrNotFound
+			},
+			expectedErr: repository.ErrNotFound,
+		},
+		{
+			name: "Loader Error - Nothing Cached",
+			setupMock: func(env *testEnv) {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+					Return(cache.ErrCacheMiss)
+			},
+			loader: func(ctx context.Context) (user, error) {
+				return user{}, errors.New("db error")
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			env := setUpTestEnv(t)
+			defer tearDownTestEnv(env)
+			tc.setupMock(env)
+
+			val, err := cache.NewTyped[user](env.mockCache).GetOrLoad(context.Background(), "user:1", time.Minute, tc.loader)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedVal, val)
+		})
+	}
+}
+
+func TestTyped_GetOrLoad_CollapsesConcurrentMisses(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	env.mockCache.EXPECT().
+		Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+		Return(cache.ErrCacheMiss).
+		AnyTimes()
+	env.mockCache.EXPECT().
+		Set(gomock.Any(), "user:1", user{ID: "1"}, time.Minute, cache.CachedTimeout).
+		Times(1)
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (user, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return user{ID: "1"}, nil
+	}
+
+	typed := cache.NewTyped[user](env.mockCache)
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]user, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = typed.GetOrLoad(context.Background(), "user:1", time.Minute, loader)
+		}(i)
+	}
+
+	// Give every caller time to join the in-flight load before releasing it.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, r := range results {
+		assert.Equal(t, user{ID: "1"}, r)
+	}
+}
+
+func TestTyped_GetOrLoad_CallerContextCanceled(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	env.mockCache.EXPECT().
+		Get(gomock.Any(), "user:1", gomock.Any(), cache.CachedTimeout).
+		Return(cache.ErrCacheMiss)
+	env.mockCache.EXPECT().
+		Set(gomock.Any(), "user:1", gomock.Any(), time.Minute, cache.CachedTimeout).
+		AnyTimes()
+
+	release := make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := cache.NewTyped[user](env.mockCache).GetOrLoad(ctx, "user:1", time.Minute, func(ctx context.Context) (user, error) {
+		<-release
+		return user{ID: "1"}, nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// softMagic is the first byte of values stored with a soft expiry by GetOrLoad.
+// It is followed by the soft expiry in Unix milliseconds (8 bytes, big endian),
+// the duration of the load that produced the value in milliseconds (4 bytes,
+// big endian), and the value encoded as usual, with its own header.
+const softMagic byte = 0x02
+
+// softHeaderSize is the length of the header preceding values stored with a soft expiry.
+const softHeaderSize = 13
+
+// DefaultEarlyRefreshBeta is the usual XFetch beta: values above 1 favour earlier
+// refreshes, values below 1 later ones.
+const DefaultEarlyRefreshBeta = 1.0
+
+// softEntry wraps a value stored together with its soft expiry. RedisCacheManager
+// encodes it as a header in front of the value, so that plain Get calls on the key
+// still decode the value alone.
+type softEntry struct {
+	Value      interface{}   `json:"value"`
+	SoftExpiry time.Time     `json:"softExpiry"`
+	Delta      time.Duration `json:"delta"`
+}
+
+// LoadMeta describes where a value returned by GetOrLoadWithMeta came from.
+type LoadMeta struct {
+	Cached     bool      // The value was read from the cache rather than loaded
+	Stale      bool      // The cached value is past its soft expiry
+	Refreshing bool      // A background refresh of the value was started
+	SoftExpiry time.Time // The soft expiry of the cached value; zero if unknown
+}
+
+// loadOptions holds the settings applied by LoadOption values.
+type loadOptions struct {
+	// staleWindow is how long a value is served after its soft expiry.
+	staleWindow time.Duration
+	// beta is the XFetch beta; early refresh is disabled when zero.
+	beta float64
+}
+
+// soft reports whether values are stored with a soft expiry.
+func (o loadOptions) soft() bool {
+	return o.staleWindow > 0 || o.beta > 0
+}
+
+// LoadOption configures optional behaviour of GetOrLoad and GetOrLoadWithMeta.
+type LoadOption func(*loadOptions)
+
+// WithStaleWhileRevalidate keeps values in the cache for window past their TTL.
+// Once the TTL has elapsed the value is soft-expired: callers get the stale value
+// immediately, flagged in LoadMeta, while a single caller refreshes it in the background.
+//
+// Parameters:
+// - window (time.Duration): How long a soft-expired value may still be served.
+//
+// Returns:
+// - LoadOption: An option to pass to GetOrLoad or GetOrLoadWithMeta.
+func WithStaleWhileRevalidate(window time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.staleWindow = window
+	}
+}
+
+// WithEarlyRefresh enables probabilistic early expiration (XFetch). Each read of a fresh
+// value refreshes it in the background with a probability rising as its soft expiry
+// approaches, scaled by how long the value took to load, which spreads the refreshes of
+// a popular key over time instead of having them all happen when it expires.
+//
+// Parameters:
+// - beta (float64): The XFetch beta, typically DefaultEarlyRefreshBeta.
+//
+// Returns:
+// - LoadOption: An option to pass to GetOrLoad or GetOrLoadWithMeta.
+func WithEarlyRefresh(beta float64) LoadOption {
+	return func(o *loadOptions) {
+		o.beta = beta
+	}
+}
+
+// shouldRefreshEarly implements the XFetch test: refresh when now - delta*beta*ln(rand)
+// reaches the expiry. rand is drawn from (0, 1] so that its logarithm is finite.
+func shouldRefreshEarly(now, expiry time.Time, delta time.Duration, beta float64) bool {
+	gap := time.Duration(float64(delta) * beta * -math.Log(1-rand.Float64()))
+	return !now.Add(gap).Before(expiry)
+}
+
+// encodeSoft encodes a softEntry as the soft header followed by the encoded value.
+func (cm *RedisCacheManager) encodeSoft(entry softEntry) ([]byte, int, error) {
+	value, size, err := cm.encode(entry.Value)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data := make([]byte, softHeaderSize, softHeaderSize+len(value))
+	data[0] = softMagic
+	binary.BigEndian.PutUint64(data[1:9], uint64(entry.SoftExpiry.UnixMilli()))
+	binary.BigEndian.PutUint32(data[9:13], uint32(min(entry.Delta.Milliseconds(), math.MaxUint32)))
+	return append(data, value...), size, nil
+}
+
+// decodeSoft decodes a value stored with a soft header. The soft expiry is reported
+// only if dest is a *softEntry; any other dest receives the value alone.
+func (cm *RedisCacheManager) decodeSoft(data []byte, dest interface{}) error {
+	if entry, ok := dest.(*softEntry); ok {
+		entry.SoftExpiry = time.UnixMilli(int64(binary.BigEndian.Uint64(data[1:9])))
+		entry.Delta = time.Duration(binary.BigEndian.Uint32(data[9:13])) * time.Millisecond
+		dest = entry.Value
+	}
+	return cm.decode(data[softHeaderSize:], dest)
+}
@@ -0,0 +1,116 @@
+package cache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLoader returns a loader numbering the users it loads.
+func countingLoader(calls *atomic.Int32, delay time.Duration) func(ctx context.Context) (user, error) {
+	return func(ctx context.Context) (user, error) {
+		time.Sleep(delay)
+		n := calls.Add(1)
+		return user{ID: "1", Name: string(rune('A' + n - 1))}, nil
+	}
+}
+
+func TestTyped_GetOrLoadWithMeta_StaleWhileRevalidate(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+	typed := cache.NewTyped[user](cm)
+	var calls atomic.Int32
+	loader := countingLoader(&calls, 0)
+	opt := cache.WithStaleWhileRevalidate(time.Minute)
+
+	val, meta, err := typed.GetOrLoadWithMeta(ctx, "user:1", 50*time.Millisecond, loader, opt)
+	require.NoError(t, err)
+	assert.Equal(t, "A", val.Name)
+	assert.Equal(t, cache.LoadMeta{}, meta)
+	// The value outlives its TTL by the stale window in Redis.
+	assert.Equal(t, time.Minute+50*time.Millisecond, server.TTL("user:1"))
+
+	val, meta, err = typed.GetOrLoadWithMeta(ctx, "user:1", 50*time.Millisecond, loader, opt)
+	require.NoError(t, err)
+	assert.Equal(t, "A", val.Name)
+	assert.True(t, meta.Cached)
+	assert.False(t, meta.Stale)
+	assert.False(t, meta.SoftExpiry.IsZero())
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Past the soft expiry the stale value is served while it is refreshed in the background.
+	val, meta, err = typed.GetOrLoadWithMeta(ctx, "user:1", 50*time.Millisecond, loader, opt)
+	require.NoError(t, err)
+	assert.Equal(t, "A", val.Name)
+	assert.True(t, meta.Stale)
+	assert.True(t, meta.Refreshing)
+
+	assert.Eventually(t, func() bool {
+		val, meta, err := typed.GetOrLoadWithMeta(ctx, "user:1", time.Minute, loader, opt)
+		return err == nil && val.Name == "B" && !meta.Stale
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Plain reads of the key see the value alone.
+	var plain user
+	assert.NoError(t, cm.Get(ctx, "user:1", &plain, time.Second))
+	assert.Equal(t, "B", plain.Name)
+}
+
+func TestTyped_GetOrLoadWithMeta_SoftExpiredWithoutWindow(t *testing.T) {
+	cm, server := setUpRedis(t)
+	ctx := context.Background()
+	typed := cache.NewTyped[user](cm)
+	var calls atomic.Int32
+	loader := countingLoader(&calls, 0)
+	// A tiny beta keeps early refreshes out of the way.
+	opt := cache.WithEarlyRefresh(1e-9)
+
+	_, _, err := typed.GetOrLoadWithMeta(ctx, "user:1", 50*time.Millisecond, loader, opt)
+	require.NoError(t, err)
+	// Keep the key in Redis past its soft expiry, as a clock skew between instances would.
+	server.SetTTL("user:1", time.Hour)
+	time.Sleep(100 * time.Millisecond)
+
+	// Soft-expired values are reloaded synchronously when no stale window is configured.
+	val, meta, err := typed.GetOrLoadWithMeta(ctx, "user:1", time.Hour, loader, opt)
+	require.NoError(t, err)
+	assert.False(t, meta.Cached)
+	assert.Equal(t, "B", val.Name)
+}
+
+func TestTyped_GetOrLoadWithMeta_EarlyRefresh(t *testing.T) {
+	cm, _ := setUpRedis(t)
+	ctx := context.Background()
+	typed := cache.NewTyped[user](cm)
+	var calls atomic.Int32
+	loader := countingLoader(&calls, 5*time.Millisecond)
+
+	_, _, err := typed.GetOrLoadWithMeta(ctx, "user:1", time.Minute, loader, cache.WithEarlyRefresh(cache.DefaultEarlyRefreshBeta))
+	require.NoError(t, err)
+
+	// With the default beta, a value a minute away from expiry that loads in
+	// milliseconds is practically never refreshed early.
+	for i := 0; i < 100; i++ {
+		_, meta, err := typed.GetOrLoadWithMeta(ctx, "user:1", time.Minute, loader, cache.WithEarlyRefresh(cache.DefaultEarlyRefreshBeta))
+		require.NoError(t, err)
+		assert.False(t, meta.Refreshing)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	// A huge beta makes the refresh certain.
+	val, meta, err := typed.GetOrLoadWithMeta(ctx, "user:1", time.Minute, loader, cache.WithEarlyRefresh(1e9))
+	require.NoError(t, err)
+	assert.Equal(t, "A", val.Name)
+	assert.True(t, meta.Cached)
+	assert.False(t, meta.Stale)
+	assert.True(t, meta.Refreshing)
+	assert.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,457 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultLocalSize is the default maximum number of entries kept in the in-process tier.
+	DefaultLocalSize = 10000
+
+	// DefaultLocalTTL is the default time-to-live of entries in the in-process tier.
+	// Set to 30 seconds.
+	DefaultLocalTTL = 30 * time.Second
+)
+
+// TieredConfig contains the settings of the in-process tier of a Tiered cache.
+type TieredConfig struct {
+	Size int           // Maximum number of entries kept in process; DefaultLocalSize if <= 0
+	TTL  time.Duration // Time-to-live of in-process entries; DefaultLocalTTL if <= 0
+
+	// Bus, if set, broadcasts the keys written or invalidated by this instance so that
+	// other instances evict them from their in-process tier. See Tiered.Listen.
+	Bus *InvalidationBus
+}
+
+// TieredStats holds the hit and miss counters of each tier of a Tiered cache.
+type TieredStats struct {
+	LocalHits    uint64 // Lookups answered by the in-process tier
+	LocalMisses  uint64 // Lookups not answered by the in-process tier
+	RemoteHits   uint64 // In-process misses answered by Redis
+	RemoteMisses uint64 // In-process misses not found in Redis either
+}
+
+// Tiered is a two-tier implementation of RedisCache keeping a bounded in-process LRU
+// in front of a RedisCacheManager.
+//
+// Hot keys are served from process memory without a round trip to Redis. Local entries
+// hold the raw encoded value and expire after the configured TTL, and never later than
+// the key expires in Redis. Local hits do not refresh the TTL of sliding keys in Redis, so
+// their local entries live at most half of the remaining TTL, letting a key read throughout
+// slide in Redis before it expires there. Writes and invalidations go through to Redis and update the
+// local tier of this instance. Without an InvalidationBus, other instances keep their local
+// copy until it expires, so the local TTL bounds how stale a value can be across instances;
+// with one, they evict it as soon as the invalidation is received.
+type Tiered struct {
+	// remote is the Redis tier.
+	remote *RedisCacheManager
+	// local is the in-process tier.
+	local *lru
+	// ttl is the maximum time-to-live of in-process entries.
+	ttl time.Duration
+	// bus broadcasts invalidations to other instances, if set.
+	bus *InvalidationBus
+	// logger provides structured logging for this Tiered cache's operations.
+	logger logger.Logger
+
+	localHits    atomic.Uint64
+	localMisses  atomic.Uint64
+	remoteHits   atomic.Uint64
+	remoteMisses atomic.Uint64
+}
+
+// NewTiered creates and returns a new Tiered cache.
+//
+// Parameters:
+// - remote (*RedisCacheManager): The Redis tier.
+// - config (TieredConfig): The settings of the in-process tier.
+// - logger (logger.Logger): A logger instance for logging cache activities and errors.
+//
+// Returns:
+// - *Tiered: An initialized Tiered cache.
+func NewTiered(remote *RedisCacheManager, config TieredConfig, logger logger.Logger) *Tiered {
+	if config.Size <= 0 {
+		config.Size = DefaultLocalSize
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultLocalTTL
+	}
+	return &Tiered{
+		remote: remote,
+		local:  newLRU(config.Size),
+		ttl:    config.TTL,
+		bus:    config.Bus,
+		logger: logger,
+	}
+}
+
+// Stats returns a snapshot of the hit and miss counters of each tier.
+func (t *Tiered) Stats() TieredStats {
+	return TieredStats{
+		LocalHits:    t.localHits.Load(),
+		LocalMisses:  t.localMisses.Load(),
+		RemoteHits:   t.remoteHits.Load(),
+		RemoteMisses: t.remoteMisses.Load(),
+	}
+}
+
+// Listen applies the invalidations published by other instances to the in-process tier
+// until the context is cancelled, resubscribing after connection losses. The whole
+// in-process tier is dropped each time the subscription is (re)established, since
+// invalidations may have been missed in the meantime. It is typically run in its own goroutine.
+//
+// Parameters:
+// - ctx (context.Context): The context controlling the lifetime of the subscription.
+//
+// Returns:
+// - error: The context's error once it is cancelled, or nil immediately if no bus is configured.
+func (t *Tiered) Listen(ctx context.Context) error {
+	if t.bus == nil {
+		return nil
+	}
+	return t.bus.Subscribe(ctx, t.apply)
+}
+
+// apply evicts the entries described by an invalidation from the in-process tier.
+func (t *Tiered) apply(invalidation Invalidation) {
+	if invalidation.Flush {
+		t.local.purge()
+		return
+	}
+	t.local.delete(invalidation.Keys...)
+	for _, prefix := range invalidation.Prefixes {
+		t.local.deletePrefix(prefix)
+	}
+}
+
+// publish broadcasts an invalidation to other instances, if a bus is configured.
+// A failure is logged but not returned: the write itself succeeded, and other
+// instances still drop their copy once the local TTL elapses.
+func (t *Tiered) publish(ctx context.Context, invalidation Invalidation) {
+	if t.bus == nil {
+		return
+	}
+	if err := t.bus.Publish(ctx, invalidation); err != nil {
+		t.logger.Warn("Failed to publish cache invalidation",
+			zap.Strings("keys", invalidation.Keys),
+			zap.Strings("prefixes", invalidation.Prefixes),
+			zap.Error(err))
+	}
+}
+
+// localTTL returns the time-to-live of an in-process entry for a key expiring in Redis after remaining.
+// A non-positive remaining duration means the key has no expiry in Redis.
+//
+// Local hits do not reach Redis and so do not refresh the TTL of sliding keys. Their local
+// entries therefore live at most half of the remaining TTL, so that a key read throughout is
+// read again from Redis, sliding its TTL, before it expires there.
+func (t *Tiered) localTTL(key string, remaining time.Duration) time.Duration {
+	if remaining > 0 && t.remote.sliding(key) {
+		remaining = max(remaining/2, time.Millisecond)
+	}
+	if remaining > 0 && remaining < t.ttl {
+		return remaining
+	}
+	return t.ttl
+}
+
+// Get retrieves a value from the in-process tier, or from Redis on a local miss, applying a timeout
+// to the Redis call. Values found in Redis are kept in process for subsequent lookups.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The key of the cache entry to retrieve.
+// - dest (interface{}): A pointer to the variable where the retrieved value should be decoded.
+// - timeout (time.Duration): The maximum time allowed for the Redis call.
+//
+// Returns:
+//   - error: An error if the operation fails, times out, or the key is not found.
+//     repository.ErrNotFound is returned if the key holds a tombstone written by SetNotFound.
+func (t *Tiered) Get(ctx context.Context, key string, dest interface{}, timeout time.Duration) error {
+	if data, ok := t.local.get(key); ok {
+		t.localHits.Add(1)
+		return t.remote.decodeValue(key, data, dest)
+	}
+	t.localMisses.Add(1)
+
+	data, remaining, err := t.remote.getWithTTL(ctx, key, timeout)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			t.remoteMisses.Add(1)
+		}
+		return err
+	}
+	t.remoteHits.Add(1)
+
+	t.local.set(key, data, t.localTTL(key, remaining))
+	return t.remote.decodeValue(key, data, dest)
+}
+
+// Set sets a value in Redis and in the in-process tier with a specified TTL, applying a timeout.
+// If the Redis write fails, the local entry is dropped rather than updated.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The cache key.
+// - value (interface{}): The value to cache.
+// - ttl (time.Duration): The time-to-live for the cached value.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - error: An error if the value cannot be encoded, or if the Redis write fails or times out.
+func (t *Tiered) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, timeout time.Duration) error {
+	return t.set(ctx, key, value, ttl, timeout, nil)
+}
+
+// set writes a value through to Redis and keeps the stored bytes in process on success.
+func (t *Tiered) set(
+	ctx context.Context,
+	key string,
+	value interface{},
+	ttl time.Duration,
+	timeout time.Duration,
+	tags []string,
+) error {
+	data, err := t.remote.set(ctx, key, value, ttl, timeout, tags)
+	if err != nil {
+		t.local.delete(key)
+		return err
+	}
+	t.local.set(key, data, t.localTTL(key, ttl))
+	t.publish(ctx, Invalidation{Keys: []string{key}})
+	return nil
+}
+
+// Delete removes a value from the in-process tier and from Redis, applying a timeout.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The cache key to delete.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - error: An error if the operation fails or times out.
+func (t *Tiered) Delete(ctx context.Context, key string, timeout time.Duration) error {
+	t.local.delete(key)
+	err := t.remote.Delete(ctx, key, timeout)
+	t.publish(ctx, Invalidation{Keys: []string{key}})
+	return err
+}
+
+// GetFromCache retrieves data from the cache if it exists.
+// It behaves like RedisCacheManager.GetFromCache, including its logging.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key used to retrieve the data from the cache.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//   - target (interface{}): A pointer to the object where the cached data will be stored.
+//
+// Returns:
+//   - bool: True if the data was found in the cache, false otherwise.
+//   - error: An error if the cache operation fails, or nil if successful.
+func (t *Tiered) GetFromCache(ctx context.Context, key string, logger logger.Logger, target interface{}) (bool, error) {
+	return getFromCache(ctx, t, key, logger, target)
+}
+
+// SetCache stores data in the cache with the specified key, associating it with optional tags.
+// It behaves like RedisCacheManager.SetCache, including its logging.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key under which the data will be stored in the cache.
+//   - data (interface{}): The data to be stored in the cache.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//   - tags (...string): The tags to associate with the key.
+//
+// Returns:
+//   - error: An error if the data cannot be encoded or stored, or nil if successful.
+func (t *Tiered) SetCache(
+	ctx context.Context,
+	key string,
+	data interface{},
+	logger logger.Logger,
+	tags ...string,
+) error {
+	if err := t.set(ctx, key, data, t.remote.ttl(key), CachedTimeout, tags); err != nil {
+		logger.Error("Failed to cache data", zap.String("cacheKey", key), zap.Error(err))
+		return err
+	}
+	logger.Info("Data cached successfully", zap.String("cacheKey", key))
+	return nil
+}
+
+// InvalidateCache removes cached data for a specific key from both tiers.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key for which the cached data should be invalidated.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the cache invalidation fails, or nil if successful.
+func (t *Tiered) InvalidateCache(ctx context.Context, key string, logger logger.Logger) error {
+	err := t.Delete(ctx, key, CachedTimeout)
+	if err != nil {
+		logger.Error("Failed to invalidate cache", zap.Error(err))
+		return err
+	}
+	logger.Info("Cache invalidated successfully", zap.String("cacheKey", key))
+	return nil
+}
+
+// SetNotFound stores a tombstone in Redis and in the in-process tier, applying a timeout.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The cache key.
+// - ttl (time.Duration): The time-to-live for the tombstone.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - error: An error if the tombstone could not be written to Redis, or nil if successful.
+func (t *Tiered) SetNotFound(ctx context.Context, key string, ttl time.Duration, timeout time.Duration) error {
+	if err := t.remote.setNotFound(ctx, key, ttl, timeout); err != nil {
+		t.local.delete(key)
+		return err
+	}
+	t.local.set(key, []byte(tombstone), t.localTTL(key, ttl))
+	t.publish(ctx, Invalidation{Keys: []string{key}})
+	return nil
+}
+
+// SetNotFoundCache stores a tombstone for the specified key with the NotFoundLifetime TTL.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key under which the tombstone will be stored in the cache.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the tombstone could not be written, or nil if successful.
+func (t *Tiered) SetNotFoundCache(ctx context.Context, key string, logger logger.Logger) error {
+	if err := t.SetNotFound(ctx, key, NotFoundLifetime, CachedTimeout); err != nil {
+		logger.Error("Failed to cache not found", zap.String("cacheKey", key), zap.Error(err))
+		return err
+	}
+	logger.Info("Not found cached successfully", zap.String("cacheKey", key))
+	return nil
+}
+
+// GetMany retrieves several values, serving what it can from the in-process tier and
+// fetching the rest from Redis in a single pipelined round trip.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - keys ([]string): The keys of the cache entries to retrieve.
+// - destFactory (func() interface{}): A function returning a new pointer to decode each hit into.
+//
+// Returns:
+// - *BatchResult: The hits, misses and known-missing keys.
+// - error: An error if the Redis pipeline fails or times out.
+func (t *Tiered) GetMany(ctx context.Context, keys []string, destFactory func() interface{}) (*BatchResult, error) {
+	values := make([][]byte, len(keys))
+	var remoteKeys []string
+	var remoteIdx []int
+	for i, key := range keys {
+		if data, ok := t.local.get(key); ok {
+			t.localHits.Add(1)
+			values[i] = data
+			continue
+		}
+		t.localMisses.Add(1)
+		remoteKeys = append(remoteKeys, key)
+		remoteIdx = append(remoteIdx, i)
+	}
+
+	if len(remoteKeys) > 0 {
+		remoteValues, remaining, err := t.remote.getManyWithTTL(ctx, remoteKeys, true)
+		if err != nil {
+			return nil, err
+		}
+		for j, data := range remoteValues {
+			if data == nil {
+				t.remoteMisses.Add(1)
+				continue
+			}
+			t.remoteHits.Add(1)
+			t.local.set(remoteKeys[j], data, t.localTTL(remoteKeys[j], remaining[j]))
+			values[remoteIdx[j]] = data
+		}
+	}
+
+	return t.remote.decodeMany(keys, values, destFactory), nil
+}
+
+// SetMany stores several values in Redis and in the in-process tier with the same TTL.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - values (map[string]interface{}): The values to cache, keyed by cache key.
+// - ttl (time.Duration): The time-to-live for the cached values.
+//
+// Returns:
+// - error: The first encoding or Redis error encountered, or nil if every value was written.
+func (t *Tiered) SetMany(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	written, err := t.remote.setMany(ctx, values, ttl)
+	keys := make([]string, 0, len(written))
+	for key := range values {
+		if data, ok := written[key]; ok {
+			t.local.set(key, data, t.localTTL(key, ttl))
+			keys = append(keys, key)
+		} else {
+			t.local.delete(key)
+		}
+	}
+	t.publish(ctx, Invalidation{Keys: keys})
+	return err
+}
+
+// InvalidateTag removes every cached entry associated with the tag from both tiers.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - tag (string): The tag whose cached entries should be invalidated.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the cache invalidation fails, or nil if successful.
+func (t *Tiered) InvalidateTag(ctx context.Context, tag string, logger logger.Logger) error {
+	keys, err := t.remote.invalidateTag(ctx, tag)
+	if err != nil {
+		logger.Error("Failed to invalidate cache tag", zap.String("tag", tag), zap.Error(err))
+		return err
+	}
+	t.local.delete(keys...)
+	t.publish(ctx, Invalidation{Keys: keys})
+	logger.Info("Cache tag invalidated successfully", zap.String("tag", tag), zap.Int("keys", len(keys)))
+	return nil
+}
+
+// InvalidatePrefix removes every cached entry whose key starts with the prefix from both tiers.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - prefix (string): The key prefix of the cached entries to invalidate.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the cache invalidation fails, or nil if successful.
+func (t *Tiered) InvalidatePrefix(ctx context.Context, prefix string, logger logger.Logger) error {
+	t.local.deletePrefix(prefix)
+	deleted, err := t.remote.invalidatePrefix(ctx, prefix)
+	t.publish(ctx, Invalidation{Prefixes: []string{prefix}})
+	if err != nil {
+		logger.Error("Failed to invalidate cache prefix", zap.String("prefix", prefix), zap.Error(err))
+		return err
+	}
+	logger.Info("Cache prefix invalidated successfully", zap.String("prefix", prefix), zap.Int("keys", deleted))
+	return nil
+}
@@ -0,0 +1,156 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/kmmania/er_commonlib/pkg/cache"
+	"github.com/kmmania/er_commonlib/pkg/repository"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+var _ cache.RedisCache = (*cache.Tiered)(nil)
+
+func setUpTiered(t *testing.T, config cache.TieredConfig) (*cache.Tiered, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	return cache.NewTiered(newManager(server), config, zap.NewNop()), server
+}
+
+func TestTiered_Get(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{})
+	ctx := context.Background()
+
+	tiered.Set(ctx, "user:1", user{ID: "1", Name: "Ada"}, time.Minute, time.Second)
+
+	// Served from process memory, even once Redis is gone.
+	server.Close()
+	var got user
+	assert.NoError(t, tiered.Get(ctx, "user:1", &got, time.Second))
+	assert.Equal(t, user{ID: "1", Name: "Ada"}, got)
+	assert.Equal(t, cache.TieredStats{LocalHits: 1}, tiered.Stats())
+}
+
+func TestTiered_Get_FillsLocalTierFromRedis(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{})
+	ctx := context.Background()
+
+	assert.NoError(t, newManager(server).SetMany(ctx, map[string]interface{}{"user:1": user{ID: "1"}}, time.Minute))
+
+	var got user
+	assert.ErrorIs(t, tiered.Get(ctx, "user:2", &got, time.Second), cache.ErrCacheMiss)
+	assert.NoError(t, tiered.Get(ctx, "user:1", &got, time.Second))
+	assert.NoError(t, tiered.Get(ctx, "user:1", &got, time.Second))
+	assert.Equal(t, user{ID: "1"}, got)
+	assert.Equal(t, cache.TieredStats{LocalHits: 1, LocalMisses: 2, RemoteHits: 1, RemoteMisses: 1}, tiered.Stats())
+}
+
+func TestTiered_Get_LocalExpiryBoundedByRedisTTL(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{TTL: time.Hour})
+	ctx := context.Background()
+
+	newManager(server).Set(ctx, "user:1", user{ID: "1"}, 50*time.Millisecond, time.Second)
+
+	var got user
+	assert.NoError(t, tiered.Get(ctx, "user:1", &got, time.Second))
+
+	time.Sleep(100 * time.Millisecond)
+	server.FastForward(100 * time.Millisecond)
+
+	assert.ErrorIs(t, tiered.Get(ctx, "user:1", &got, time.Second), cache.ErrCacheMiss)
+	assert.Equal(t, uint64(0), tiered.Stats().LocalHits)
+}
+
+func TestTiered_Get_LocalTTL(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{TTL: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	tiered.Set(ctx, "user:1", user{ID: "1"}, time.Minute, time.Second)
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, server.Set("user:1", `{"id":"2"}`))
+
+	var got user
+	assert.NoError(t, tiered.Get(ctx, "user:1", &got, time.Second))
+	assert.Equal(t, "2", got.ID)
+	assert.Equal(t, cache.TieredStats{LocalMisses: 1, RemoteHits: 1}, tiered.Stats())
+}
+
+func TestTiered_Size(t *testing.T) {
+	tiered, _ := setUpTiered(t, cache.TieredConfig{Size: 2})
+	ctx := context.Background()
+
+	tiered.Set(ctx, "a", 1, time.Minute, time.Second)
+	tiered.Set(ctx, "b", 2, time.Minute, time.Second)
+	var n int
+	assert.NoError(t, tiered.Get(ctx, "a", &n, time.Second))
+	tiered.Set(ctx, "c", 3, time.Minute, time.Second)
+
+	// "b" was the least recently used entry and was evicted from process memory.
+	for _, key := range []string{"c", "a", "b"} {
+		assert.NoError(t, tiered.Get(ctx, key, &n, time.Second))
+	}
+	assert.Equal(t, cache.TieredStats{LocalHits: 3, LocalMisses: 1, RemoteHits: 1}, tiered.Stats())
+}
+
+func TestTiered_NotFound(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{})
+	ctx := context.Background()
+
+	assert.NoError(t, tiered.SetNotFoundCache(ctx, "user:1", zap.NewNop()))
+	assert.True(t, server.Exists("user:1"))
+
+	var got user
+	assert.ErrorIs(t, tiered.Get(ctx, "user:1", &got, time.Second), repository.ErrNotFound)
+	assert.Equal(t, cache.TieredStats{LocalHits: 1}, tiered.Stats())
+}
+
+func TestTiered_Invalidation(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{})
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	tiered.SetCache(ctx, "user:1", user{ID: "1"}, log, "users")
+	tiered.SetCache(ctx, "user:2", user{ID: "2"}, log)
+	tiered.SetCache(ctx, "search:1", user{ID: "3"}, log)
+
+	assert.NoError(t, tiered.InvalidateTag(ctx, "users", log))
+	assert.NoError(t, tiered.InvalidateCache(ctx, "user:2", log))
+	assert.NoError(t, tiered.InvalidatePrefix(ctx, "search:", log))
+	assert.Empty(t, server.Keys())
+
+	var got user
+	for _, key := range []string{"user:1", "user:2", "search:1"} {
+		assert.ErrorIs(t, tiered.Get(ctx, key, &got, time.Second), cache.ErrCacheMiss)
+	}
+	assert.Equal(t, uint64(0), tiered.Stats().LocalHits)
+}
+
+func TestTiered_GetMany(t *testing.T) {
+	tiered, server := setUpTiered(t, cache.TieredConfig{})
+	ctx := context.Background()
+
+	assert.NoError(t, tiered.SetMany(ctx, map[string]interface{}{"user:1": user{ID: "1"}}, time.Minute))
+	newManager(server).Set(ctx, "user:2", user{ID: "2"}, time.Minute, time.Second)
+	assert.NoError(t, tiered.SetNotFound(ctx, "user:3", time.Minute, time.Second))
+
+	result, err := tiered.GetMany(ctx, []string{"user:1", "user:2", "user:3", "user:4"}, func() interface{} {
+		return new(user)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"user:1": &user{ID: "1"},
+		"user:2": &user{ID: "2"},
+	}, result.Hits)
+	assert.Equal(t, []string{"user:4"}, result.Misses)
+	assert.Equal(t, []string{"user:3"}, result.NotFound)
+	assert.Equal(t, cache.TieredStats{LocalHits: 2, LocalMisses: 2, RemoteHits: 1, RemoteMisses: 1}, tiered.Stats())
+
+	// "user:2" is now held in process as well.
+	server.Close()
+	_, err = tiered.GetMany(ctx, []string{"user:1", "user:2"}, func() interface{} { return new(user) })
+	assert.NoError(t, err)
+}
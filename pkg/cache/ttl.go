@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/kmmania/er_commonlib/internal/glob"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TTLRule sets the time-to-live of the keys matching a pattern.
+type TTLRule struct {
+	Pattern string        // Glob pattern matched against the whole key; '*' matches any sequence, '?' any byte
+	TTL     time.Duration // Time-to-live of the matching keys
+	Sliding bool          // Refresh the TTL of matching keys each time they are read from Redis; see SetCache for tags
+}
+
+// TTLPolicy decides the time-to-live of the keys written by SetCache.
+//
+// The first rule whose pattern matches the key applies; keys matching no rule get the
+// default TTL. Every TTL is then shortened by a random amount of up to JitterPercent
+// percent, so that keys written together, e.g. while warming up after a deploy, do not
+// all expire in the same second.
+type TTLPolicy struct {
+	Rules         []TTLRule     // Per-pattern TTLs, checked in order
+	Default       time.Duration // TTL of keys matching no rule; CachedLifetime if zero
+	JitterPercent float64       // Maximum random reduction of each TTL, in percent (0-100)
+}
+
+// slideScript reads a key and, unless it holds a tombstone, resets its expiry.
+// Tombstones keep their own short lifetime so that new records become visible quickly.
+var slideScript = redis.NewScript(`
+local value = redis.call('GET', KEYS[1])
+if value and value ~= ARGV[2] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return value
+`)
+
+// WithTTLPolicy sets the policy deciding the TTL of keys written by SetCache and
+// whether reading a key refreshes its TTL. Set and SetMany keep using the TTL they are given.
+//
+// Parameters:
+// - policy (TTLPolicy): The TTL policy.
+//
+// Returns:
+// - Option: An option to pass to New.
+func WithTTLPolicy(policy TTLPolicy) Option {
+	return func(cm *RedisCacheManager) {
+		if policy.Default <= 0 {
+			policy.Default = CachedLifetime
+		}
+		policy.JitterPercent = min(max(policy.JitterPercent, 0), 100)
+		cm.ttlPolicy = &policy
+	}
+}
+
+// rule returns the rule applying to the key, or a non-sliding rule with the default TTL.
+func (p *TTLPolicy) rule(key string) TTLRule {
+	for _, rule := range p.Rules {
+		if glob.Match(rule.Pattern, key) {
+			return rule
+		}
+	}
+	return TTLRule{TTL: p.Default}
+}
+
+// jitter shortens the TTL by a random amount of up to JitterPercent percent.
+func (p *TTLPolicy) jitter(ttl time.Duration) time.Duration {
+	if p.JitterPercent == 0 {
+		return ttl
+	}
+	return ttl - time.Duration(rand.Float64()*p.JitterPercent/100*float64(ttl))
+}
+
+// ttl returns the TTL SetCache applies to the key.
+func (cm *RedisCacheManager) ttl(key string) time.Duration {
+	if cm.ttlPolicy == nil {
+		return CachedLifetime
+	}
+	return cm.ttlPolicy.jitter(cm.ttlPolicy.rule(key).TTL)
+}
+
+// sliding reports whether reading the key refreshes its TTL.
+func (cm *RedisCacheManager) sliding(key string) bool {
+	return cm.ttlPolicy != nil && cm.ttlPolicy.rule(key).Sliding
+}
+
+// queueGet reads the key, refreshing its TTL if the policy makes it sliding. The command
+// runs immediately on a client and on Exec on a pipeline; the returned function yields its
+// result once it has run, with redis.Nil if the key does not exist.
+func (cm *RedisCacheManager) queueGet(ctx context.Context, cmdable redis.Cmdable, key string) func() ([]byte, error) {
+	if cm.ttlPolicy != nil {
+		if rule := cm.ttlPolicy.rule(key); rule.Sliding {
+			ttl := cm.ttlPolicy.jitter(rule.TTL)
+			cmd := slideScript.Eval(ctx, cmdable, []string{cm.key(key)}, ttl.Milliseconds(), tombstone)
+			return func() ([]byte, error) {
+				value, err := cmd.Text()
+				return []byte(value), err
+			}
+		}
+	}
+	return cmdable.Get(ctx, cm.key(key)).Bytes
+}
@@ -0,0 +1,162 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRedisCacheManager_TTLPolicy(t *testing.T) {
+	policy := cache.TTLPolicy{
+		Rules: []cache.TTLRule{
+			{Pattern: "session:*", TTL: 15 * time.Minute},
+			{Pattern: "user:*:profile", TTL: 10 * time.Minute},
+			{Pattern: "user:?", TTL: 5 * time.Minute},
+			{Pattern: "literal*star", TTL: time.Minute},
+		},
+		Default: 30 * time.Minute,
+	}
+
+	testCases := []struct {
+		key      string
+		expected time.Duration
+	}{
+		{key: "session:abc", expected: 15 * time.Minute},
+		{key: "session:", expected: 15 * time.Minute},
+		{key: "user:42:profile", expected: 10 * time.Minute},
+		{key: "user:4:2:profile", expected: 10 * time.Minute},
+		{key: "user:42:profile:x", expected: 30 * time.Minute},
+		{key: "user:4", expected: 5 * time.Minute},
+		{key: "user:42", expected: 30 * time.Minute},
+		{key: "literal*star", expected: time.Minute},
+		{key: "literal-and-star", expected: time.Minute},
+		{key: "other", expected: 30 * time.Minute},
+	}
+
+	server := miniredis.RunT(t)
+	cm := newManager(server, cache.WithTTLPolicy(policy))
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			require.NoError(t, cm.SetCache(context.Background(), tc.key, "cached", zap.NewNop()))
+			assert.Equal(t, tc.expected, server.TTL(tc.key))
+		})
+	}
+}
+
+func TestRedisCacheManager_TTLPolicy_Defaults(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.Background()
+
+	require.NoError(t, newManager(server).SetCache(ctx, "a", "cached", zap.NewNop()))
+	assert.Equal(t, cache.CachedLifetime, server.TTL("a"))
+
+	require.NoError(t, newManager(server, cache.WithTTLPolicy(cache.TTLPolicy{})).SetCache(ctx, "b", "cached", zap.NewNop()))
+	assert.Equal(t, cache.CachedLifetime, server.TTL("b"))
+}
+
+func TestRedisCacheManager_TTLPolicy_Jitter(t *testing.T) {
+	server := miniredis.RunT(t)
+	cm := newManager(server, cache.WithTTLPolicy(cache.TTLPolicy{Default: time.Hour, JitterPercent: 10}))
+	ctx := context.Background()
+
+	ttls := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		require.NoError(t, cm.SetCache(ctx, "key", "cached", zap.NewNop()))
+		ttl := server.TTL("key")
+		assert.LessOrEqual(t, ttl, time.Hour)
+		assert.GreaterOrEqual(t, ttl, 54*time.Minute)
+		ttls[ttl] = true
+	}
+	assert.Greater(t, len(ttls), 1, "TTLs are spread")
+}
+
+func TestRedisCacheManager_TTLPolicy_Sliding(t *testing.T) {
+	server := miniredis.RunT(t)
+	cm := newManager(server, cache.WithTTLPolicy(cache.TTLPolicy{
+		Rules: []cache.TTLRule{{Pattern: "session:*", TTL: time.Minute, Sliding: true}},
+	}))
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	require.NoError(t, cm.SetCache(ctx, "session:1", "cached", log))
+	require.NoError(t, cm.SetCache(ctx, "user:1", "cached", log))
+	require.NoError(t, cm.SetNotFoundCache(ctx, "session:2", log))
+	server.FastForward(50 * time.Second)
+
+	// Reading a sliding key resets its TTL, through every read path.
+	var value string
+	assert.NoError(t, cm.Get(ctx, "session:1", &value, time.Second))
+	assert.Equal(t, time.Minute, server.TTL("session:1"))
+
+	server.FastForward(50 * time.Second)
+	_, err := cm.GetMany(ctx, []string{"session:1", "session:2"}, func() interface{} { return new(string) })
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, server.TTL("session:1"))
+
+	server.FastForward(50 * time.Second)
+	tiered := cache.NewTiered(cm, cache.TieredConfig{}, log)
+	assert.NoError(t, tiered.Get(ctx, "session:1", &value, time.Second))
+	assert.Equal(t, time.Minute, server.TTL("session:1"))
+
+	// Other keys and tombstones keep their expiry.
+	assert.NoError(t, cm.Get(ctx, "user:1", &value, time.Second))
+	assert.Equal(t, cache.CachedLifetime-150*time.Second, server.TTL("user:1"))
+	assert.False(t, server.Exists("session:2"))
+
+	assert.ErrorIs(t, cm.Get(ctx, "session:missing", &value, time.Second), cache.ErrCacheMiss)
+}
+
+func TestRedisCacheManager_TTLPolicy_SlidingTags(t *testing.T) {
+	server := miniredis.RunT(t)
+	cm := newManager(server, cache.WithTTLPolicy(cache.TTLPolicy{
+		Rules: []cache.TTLRule{{Pattern: "session:*", TTL: time.Minute, Sliding: true}},
+	}))
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	require.NoError(t, cm.SetCache(ctx, "session:1", "cached", log, "sessions", "devices"))
+	require.NoError(t, cm.SetCache(ctx, "user:1", "cached", log, "sessions"))
+
+	// Slide the key past its original TTL.
+	var value string
+	server.FastForward(50 * time.Second)
+	require.NoError(t, cm.Get(ctx, "session:1", &value, time.Second))
+	server.FastForward(50 * time.Second)
+	require.True(t, server.Exists("session:1"))
+
+	// The tag set outlived the original TTL along with the key, but still expires.
+	assert.Equal(t, cache.SlidingTagLifetimeFactor*time.Minute-100*time.Second, server.TTL("cache:tag:devices"))
+	require.NoError(t, cm.InvalidateTag(ctx, "sessions", log))
+	assert.False(t, server.Exists("session:1"))
+	assert.ErrorIs(t, cm.Get(ctx, "session:1", &value, time.Second), cache.ErrCacheMiss)
+}
+
+func TestTiered_TTLPolicy_Sliding(t *testing.T) {
+	server := miniredis.RunT(t)
+	cm := newManager(server, cache.WithTTLPolicy(cache.TTLPolicy{
+		Rules: []cache.TTLRule{{Pattern: "session:*", TTL: 200 * time.Millisecond, Sliding: true}},
+	}))
+	tiered := cache.NewTiered(cm, cache.TieredConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	require.NoError(t, cm.SetCache(ctx, "session:1", "cached", zap.NewNop()))
+	var value string
+	require.NoError(t, tiered.Get(ctx, "session:1", &value, time.Second))
+
+	// Local hits do not slide the TTL in Redis...
+	server.FastForward(150 * time.Millisecond)
+	require.NoError(t, tiered.Get(ctx, "session:1", &value, time.Second))
+	assert.Equal(t, 50*time.Millisecond, server.TTL("session:1"))
+
+	// ...but the local entry expires after half the TTL, so the next read slides it.
+	time.Sleep(120 * time.Millisecond)
+	require.NoError(t, tiered.Get(ctx, "session:1", &value, time.Second))
+	assert.Equal(t, 200*time.Millisecond, server.TTL("session:1"))
+}
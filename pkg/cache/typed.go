@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Typed is a type-safe view over a RedisCache for values of type T.
+//
+// It removes the need to pass an untyped destination to Get and GetFromCache:
+// values are always decoded into a freshly allocated T, so callers can no longer
+// pass a value instead of a pointer or the wrong struct by mistake.
+//
+// A Typed cache must not be copied after first use; share it by pointer so that
+// concurrent GetOrLoad calls for the same key are collapsed.
+type Typed[T any] struct {
+	// cache is the underlying cache used to store and retrieve values.
+	cache RedisCache
+	// group collapses concurrent loads of the same key within this process.
+	group singleflight.Group
+}
+
+// NewTyped creates and returns a new Typed cache for values of type T.
+//
+// Parameters:
+// - cache (RedisCache): The underlying cache, typically a *RedisCacheManager.
+//
+// Returns:
+// - *Typed[T]: An initialized Typed cache.
+func NewTyped[T any](cache RedisCache) *Typed[T] {
+	return &Typed[T]{cache: cache}
+}
+
+// Get retrieves a value of type T from the cache by its key, applying a timeout.
+// A cache miss is reported as found == false with a nil error; ErrCacheMiss is
+// never returned to the caller. A tombstone is reported as repository.ErrNotFound.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The key of the cache entry to retrieve.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - T: The cached value, or the zero value of T if it was not found.
+// - bool: True if the value was found in the cache, false otherwise.
+// - error: An error if the operation fails or times out.
+func (t *Typed[T]) Get(ctx context.Context, key string, timeout time.Duration) (T, bool, error) {
+	var value T
+	err := t.cache.Get(ctx, key, &value, timeout)
+	if err != nil {
+		var zero T
+		if errors.Is(err, ErrCacheMiss) {
+			return zero, false, nil
+		}
+		return zero, false, err
+	}
+	return value, true, nil
+}
+
+// Set sets a value of type T in the cache with a specified TTL, applying a timeout.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The cache key.
+// - value (T): The value to cache.
+// - ttl (time.Duration): The time-to-live for the cached value.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - error: An error if the value cannot be encoded, or if the operation fails or times out.
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, ttl time.Duration, timeout time.Duration) error {
+	return t.cache.Set(ctx, key, value, ttl, timeout)
+}
+
+// Delete removes a value from the cache by its key, applying a timeout.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - key (string): The cache key to delete.
+// - timeout (time.Duration): The maximum time allowed for the operation.
+//
+// Returns:
+// - error: An error if the operation fails or times out.
+func (t *Typed[T]) Delete(ctx context.Context, key string, timeout time.Duration) error {
+	return t.cache.Delete(ctx, key, timeout)
+}
+
+// GetFromCache retrieves a value of type T from the cache if it exists.
+// It behaves like RedisCacheManager.GetFromCache, including its logging, but returns
+// the decoded value instead of populating a caller-supplied target.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key used to retrieve the data from the cache.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - T: The cached value, or the zero value of T if it was not found.
+//   - bool: True if the data was found in the cache, false otherwise.
+//   - error: An error if the cache operation fails, or nil if successful.
+func (t *Typed[T]) GetFromCache(ctx context.Context, key string, logger logger.Logger) (T, bool, error) {
+	var value T
+	found, err := t.cache.GetFromCache(ctx, key, logger, &value)
+	if err != nil || !found {
+		var zero T
+		return zero, found, err
+	}
+	return value, true, nil
+}
+
+// SetCache stores a value of type T in the cache with the specified key.
+// It behaves like RedisCacheManager.SetCache, including its logging.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key under which the data will be stored in the cache.
+//   - value (T): The data to be stored in the cache.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//   - tags (...string): The tags to associate with the key.
+//
+// Returns:
+//   - error: An error if the data cannot be encoded or stored, or nil if successful.
+func (t *Typed[T]) SetCache(ctx context.Context, key string, value T, logger logger.Logger, tags ...string) error {
+	return t.cache.SetCache(ctx, key, value, logger, tags...)
+}
+
+// InvalidateCache removes cached data for a specific key.
+//
+// Parameters:
+//   - ctx (context.Context): The context for the cache operation.
+//   - key (string): The key for which the cached data should be invalidated.
+//   - logger (logger.Logger): The logger instance for logging cache operations.
+//
+// Returns:
+//   - error: An error if the cache invalidation fails, or nil if successful.
+func (t *Typed[T]) InvalidateCache(ctx context.Context, key string, logger logger.Logger) error {
+	return t.cache.InvalidateCache(ctx, key, logger)
+}
+
+// GetT retrieves a value of type T from the given cache by its key, using CachedTimeout.
+// It is a shorthand for NewTyped[T](c).Get for one-off lookups.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - c (RedisCache): The cache to read from.
+// - key (string): The key of the cache entry to retrieve.
+//
+// Returns:
+// - T: The cached value, or the zero value of T if it was not found.
+// - bool: True if the value was found in the cache, false otherwise.
+// - error: An error if the operation fails or times out.
+func GetT[T any](ctx context.Context, c RedisCache, key string) (T, bool, error) {
+	return NewTyped[T](c).Get(ctx, key, CachedTimeout)
+}
+
+// SetT stores a value of type T in the given cache with a specified TTL, using CachedTimeout.
+// It is a shorthand for NewTyped[T](c).Set for one-off writes.
+//
+// Parameters:
+// - ctx (context.Context): The base context for the operation.
+// - c (RedisCache): The cache to write to.
+// - key (string): The cache key.
+// - value (T): The value to cache.
+// - ttl (time.Duration): The time-to-live for the cached value.
+//
+// Returns:
+// - error: An error if the value cannot be encoded, or if the operation fails or times out.
+func SetT[T any](ctx context.Context, c RedisCache, key string, value T, ttl time.Duration) error {
+	return NewTyped[T](c).Set(ctx, key, value, ttl, CachedTimeout)
+}
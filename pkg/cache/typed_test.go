@@ -0,0 +1,108 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/cache"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTyped_Get(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	type testCase struct {
+		name          string
+		setupMock     func()
+		key           string
+		expectedVal   user
+		expectedFound bool
+		expectedErr   error
+	}
+
+	tests := []testCase{
+		{
+			name: "Success - Value Found",
+			setupMock: func() {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:1", gomock.Any(), time.Second).
+					DoAndReturn(func(ctx context.Context, key string, dest interface{}, timeout time.Duration) error {
+						*(dest.(*user)) = user{ID: "1", Name: "Ada"}
+						return nil
+					})
+			},
+			key:           "user:1",
+			expectedVal:   user{ID: "1", Name: "Ada"},
+			expectedFound: true,
+		},
+		{
+			name: "Miss - Not Found Without Error",
+			setupMock: func() {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:2", gomock.Any(), time.Second).
+					Return(cache.ErrCacheMiss)
+			},
+			key:           "user:2",
+			expectedFound: false,
+		},
+		{
+			name: "Failure - Redis Error",
+			setupMock: func() {
+				env.mockCache.EXPECT().
+					Get(gomock.Any(), "user:3", gomock.Any(), time.Second).
+					Return(errors.New("redis error"))
+			},
+			key:         "user:3",
+			expectedErr: errors.New("redis error"),
+		},
+	}
+
+	typed := cache.NewTyped[user](env.mockCache)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupMock()
+			val, found, err := typed.Get(context.Background(), tc.key, time.Second)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expectedVal, val)
+		})
+	}
+}
+
+func TestTyped_GetFromCache(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	env.mockCache.EXPECT().
+		GetFromCache(gomock.Any(), "user:1", nil, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, key string, _ interface{}, target interface{}) (bool, error) {
+			*(target.(*user)) = user{ID: "1", Name: "Ada"}
+			return true, nil
+		})
+
+	val, found, err := cache.NewTyped[user](env.mockCache).GetFromCache(context.Background(), "user:1", nil)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, user{ID: "1", Name: "Ada"}, val)
+}
+
+func TestSetT(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	env.mockCache.EXPECT().
+		Set(gomock.Any(), "user:1", user{ID: "1"}, time.Minute, cache.CachedTimeout).
+		Times(1)
+
+	cache.SetT(context.Background(), env.mockCache, "user:1", user{ID: "1"}, time.Minute)
+}
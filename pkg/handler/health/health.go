@@ -18,11 +18,17 @@ import (
 // HealthCheckDependencies contains the external dependencies required for the health check.
 //
 // These typically include a database pool and a Redis client. Each of these can be nil,
-// in which case the related health check will be skipped and assumed healthy.
+// in which case the related health check will be skipped and assumed healthy. A nil
+// *redis.Client, *redis.ClusterClient or *redis.Ring stored in RedisClient counts as nil.
+//
+// RedisClient is a redis.UniversalClient rather than a *redis.Client, so that sentinel and
+// cluster clients can be checked. Struct literals assigning a *redis.Client still compile;
+// code reading the field as a *redis.Client, or passing it where a *redis.Client is
+// expected, must now use a type assertion or keep its own *redis.Client.
 type HealthCheckDependencies struct {
-	DBPool      db.DBTX       // Interface-based DB connection for flexibility.
-	RedisClient *redis.Client // Redis client instance.
-	Logger      logger.Logger // Logger for logging health check outcomes.
+	DBPool      db.DBTX               // Interface-based DB connection for flexibility.
+	RedisClient redis.UniversalClient // Redis client: standalone, sentinel or cluster.
+	Logger      logger.Logger         // Logger for logging health check outcomes.
 }
 
 // MakeHealthzHandler returns a Gin handler for the /healthz endpoint.
@@ -58,7 +64,7 @@ func MakeHealthzHandler(deps HealthCheckDependencies) gin.HandlerFunc {
 		}
 
 		// --- Check Redis Connectivity ---
-		if deps.RedisClient != nil {
+		if !isNilClient(deps.RedisClient) {
 			if _, err := deps.RedisClient.Ping(ctx).Result(); err != nil {
 				deps.Logger.Warn("Health check: Redis ping failed", zap.Error(err))
 				redisOk = false
@@ -99,3 +105,20 @@ func MakeHealthzHandler(deps HealthCheckDependencies) gin.HandlerFunc {
 		c.JSON(httpStatus, response)
 	}
 }
+
+// isNilClient reports whether the Redis client is nil, including a nil pointer of one of the
+// go-redis client types stored in the interface.
+func isNilClient(client redis.UniversalClient) bool {
+	switch c := client.(type) {
+	case nil:
+		return true
+	case *redis.Client:
+		return c == nil
+	case *redis.ClusterClient:
+		return c == nil
+	case *redis.Ring:
+		return c == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,309 @@
+/*
+Package lock provides a distributed lock built on Redis, giving "only one instance at a time"
+semantics to jobs running on several pods.
+
+A lock is a Redis key holding a random token, created with SET NX and an expiry. Only the
+holder of the token can refresh or release the lock, which is checked atomically by Lua
+scripts, so an instance whose lease expired can never release a lock since acquired by
+another one. While held, a lease is extended automatically in the background; if it cannot
+be extended before it expires, the lease reports the loss on its Lost channel so that the
+job can stop.
+
+The Redis client is typically built with cache.NewRedisClient, sharing the configuration
+used for RedisCacheManager.
+
+Errors:
+  - ErrLockNotAcquired: The lock is held by someone else.
+  - ErrLockNotHeld: The lease has expired or was released, and the lock may now be held by someone else.
+  - ErrInvalidTTL: The lock's time-to-live is shorter than a millisecond, the resolution of its expiry.
+*/
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrLockNotAcquired indicates that the lock is held by someone else.
+	ErrLockNotAcquired = errors.New("lock not acquired")
+
+	// ErrLockNotHeld indicates that the lease no longer holds the lock.
+	ErrLockNotHeld = errors.New("lock not held")
+
+	// ErrInvalidTTL indicates that the lock's time-to-live is shorter than a millisecond.
+	ErrInvalidTTL = errors.New("lock ttl must be at least 1ms")
+)
+
+const (
+	// KeyPrefix is the prefix of the Redis keys holding locks.
+	KeyPrefix = "lock:"
+
+	// LockTimeout represents the maximum time to wait for a single Redis call made by a lease in the background.
+	// Set to 2 seconds.
+	LockTimeout = 2 * time.Second
+)
+
+// refreshScript extends the lock's expiry if it is still held with the given token.
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock if it is still held with the given token.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Locker acquires distributed locks.
+type Locker struct {
+	// client is the Redis client holding the locks.
+	client redis.UniversalClient
+	// logger provides structured logging for this Locker's operations.
+	logger logger.Logger
+	// retryInterval is the delay between acquisition attempts; Acquire tries once if zero.
+	retryInterval time.Duration
+}
+
+// Option configures optional behaviour of a Locker.
+type Option func(*Locker)
+
+// WithRetryInterval makes Acquire wait for a lock held by someone else, trying again
+// every interval until the lock is acquired or the context is done.
+//
+// Parameters:
+// - interval (time.Duration): The delay between acquisition attempts.
+//
+// Returns:
+// - Option: An option to pass to New.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(l *Locker) {
+		l.retryInterval = interval
+	}
+}
+
+// New creates and returns a new Locker.
+//
+// Parameters:
+// - client (redis.UniversalClient): The Redis client holding the locks.
+// - logger (logger.Logger): A logger instance for logging lock activities and errors.
+// - opts (...Option): Optional settings such as the retry interval.
+//
+// Returns:
+// - *Locker: An initialized Locker.
+func New(client redis.UniversalClient, logger logger.Logger, opts ...Option) *Locker {
+	l := &Locker{client: client, logger: logger}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Acquire acquires the named lock for ttl and returns its lease, which is extended
+// automatically until it is released. The lease must be released with Release once
+// the work is done, which also stops its extension.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation; with WithRetryInterval, it bounds the wait.
+// - name (string): The name of the lock.
+// - ttl (time.Duration): How long the lock is held without being extended; at least a millisecond.
+//
+// Returns:
+// - *Lease: The lease of the acquired lock.
+// - error: ErrInvalidTTL if ttl is shorter than a millisecond, ErrLockNotAcquired if the lock is held by someone else, or the Redis error.
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	// A zero expiry would never expire, and refreshing it would delete the lock
+	if ttl < time.Millisecond {
+		return nil, ErrInvalidTTL
+	}
+	key := KeyPrefix + name
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		ok, err := l.client.SetNX(ctx, key, token, ttl).Result()
+		if err != nil {
+			l.logger.Error("Error acquiring lock", zap.String("lock", name), zap.Error(err))
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if l.retryInterval <= 0 {
+			l.logger.Debug("Lock held by another owner", zap.String("lock", name))
+			return nil, ErrLockNotAcquired
+		}
+
+		timer := time.NewTimer(l.retryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.logger.Debug("Gave up waiting for lock", zap.String("lock", name), zap.Error(ctx.Err()))
+			return nil, ErrLockNotAcquired
+		case <-timer.C:
+		}
+	}
+
+	lease := &Lease{
+		locker: l,
+		name:   name,
+		key:    key,
+		token:  token,
+		ttl:    ttl,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	go lease.extend()
+
+	l.logger.Info("Lock acquired", zap.String("lock", name), zap.Duration("ttl", ttl))
+	return lease, nil
+}
+
+// newToken returns a random token identifying a lease.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Lease is a held distributed lock.
+type Lease struct {
+	// locker is the Locker that acquired the lease.
+	locker *Locker
+	// name is the name of the lock.
+	name string
+	// key is the Redis key holding the lock.
+	key string
+	// token proves ownership of the lock.
+	token string
+	// ttl is the expiry set on each acquisition or refresh.
+	ttl time.Duration
+
+	// stopOnce guards closing stop.
+	stopOnce sync.Once
+	// stop is closed to stop the automatic extension.
+	stop chan struct{}
+	// done is closed once the automatic extension has stopped.
+	done chan struct{}
+	// lostOnce guards closing lost.
+	lostOnce sync.Once
+	// lost is closed when the lease is found to no longer hold the lock.
+	lost chan struct{}
+}
+
+// Name returns the name of the lock.
+func (l *Lease) Name() string {
+	return l.name
+}
+
+// Lost returns a channel closed when the automatic extension finds that the lease no
+// longer holds the lock, because it expired or was taken over. Work protected by the
+// lock should stop when it is closed.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh extends the lock for another ttl, provided the lease still holds it.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+//
+// Returns:
+// - error: ErrLockNotHeld if the lease no longer holds the lock, or the Redis error.
+func (l *Lease) Refresh(ctx context.Context) error {
+	ok, err := refreshScript.Run(ctx, l.locker.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		l.locker.logger.Error("Error refreshing lock", zap.String("lock", l.name), zap.Error(err))
+		return err
+	}
+	if ok == 0 {
+		l.markLost()
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release stops the automatic extension and releases the lock, provided the lease still holds it.
+//
+// Parameters:
+// - ctx (context.Context): The context for the operation.
+//
+// Returns:
+// - error: ErrLockNotHeld if the lease no longer held the lock, or the Redis error.
+func (l *Lease) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() { close(l.stop) })
+	<-l.done
+
+	ok, err := releaseScript.Run(ctx, l.locker.client, []string{l.key}, l.token).Int()
+	if err != nil {
+		l.locker.logger.Error("Error releasing lock", zap.String("lock", l.name), zap.Error(err))
+		return err
+	}
+	if ok == 0 {
+		l.locker.logger.Warn("Lock already lost when released", zap.String("lock", l.name))
+		l.markLost()
+		return ErrLockNotHeld
+	}
+
+	l.locker.logger.Info("Lock released", zap.String("lock", l.name))
+	return nil
+}
+
+// extend refreshes the lease every third of its ttl until it is released or lost.
+// Transient errors are retried at the next tick; once the ttl has elapsed since the
+// last successful refresh, the lock has expired and the lease is marked as lost.
+func (l *Lease) extend() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(max(l.ttl/3, time.Millisecond))
+	defer ticker.Stop()
+	lastRefresh := time.Now()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), min(LockTimeout, l.ttl))
+		err := l.Refresh(ctx)
+		cancel()
+
+		switch {
+		case err == nil:
+			lastRefresh = time.Now()
+		case errors.Is(err, ErrLockNotHeld):
+			l.locker.logger.Warn("Lock lost", zap.String("lock", l.name))
+			return
+		case time.Since(lastRefresh) >= l.ttl:
+			l.locker.logger.Warn("Lock expired before it could be extended", zap.String("lock", l.name), zap.Error(err))
+			l.markLost()
+			return
+		}
+	}
+}
+
+// markLost closes the lost channel once.
+func (l *Lease) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
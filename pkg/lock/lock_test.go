@@ -0,0 +1,151 @@
+package lock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/lock"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func setUpLocker(t *testing.T, opts ...lock.Option) (*lock.Locker, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return lock.New(client, zap.NewNop(), opts...), server
+}
+
+func TestLocker_Acquire(t *testing.T) {
+	locker, server := setUpLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.Acquire(ctx, "report", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "report", lease.Name())
+	assert.True(t, server.Exists("lock:report"))
+	assert.Equal(t, time.Minute, server.TTL("lock:report"))
+
+	_, err = locker.Acquire(ctx, "report", time.Minute)
+	assert.ErrorIs(t, err, lock.ErrLockNotAcquired)
+
+	// Other lock names are independent.
+	other, err := locker.Acquire(ctx, "billing", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, other.Release(ctx))
+
+	assert.NoError(t, lease.Release(ctx))
+	assert.False(t, server.Exists("lock:report"))
+	assert.ErrorIs(t, lease.Release(ctx), lock.ErrLockNotHeld)
+
+	lease, err = locker.Acquire(ctx, "report", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, lease.Release(ctx))
+}
+
+func TestLocker_Acquire_InvalidTTL(t *testing.T) {
+	locker, server := setUpLocker(t)
+	ctx := context.Background()
+
+	for _, ttl := range []time.Duration{0, -time.Second, time.Microsecond} {
+		lease, err := locker.Acquire(ctx, "report", ttl)
+		assert.ErrorIs(t, err, lock.ErrInvalidTTL, "ttl %s", ttl)
+		assert.Nil(t, lease)
+		assert.False(t, server.Exists("lock:report"))
+	}
+
+	lease, err := locker.Acquire(ctx, "report", time.Millisecond)
+	require.NoError(t, err)
+	assert.NoError(t, lease.Release(ctx))
+}
+
+func TestLocker_Acquire_RetryInterval(t *testing.T) {
+	locker, _ := setUpLocker(t, lock.WithRetryInterval(10*time.Millisecond))
+	ctx := context.Background()
+
+	lease, err := locker.Acquire(ctx, "report", time.Minute)
+	require.NoError(t, err)
+
+	// The wait is bounded by the context.
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = locker.Acquire(waitCtx, "report", time.Minute)
+	assert.ErrorIs(t, err, lock.ErrLockNotAcquired)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = lease.Release(ctx)
+	}()
+	next, err := locker.Acquire(ctx, "report", time.Minute)
+	require.NoError(t, err)
+	assert.NoError(t, next.Release(ctx))
+}
+
+func TestLease_Refresh(t *testing.T) {
+	locker, server := setUpLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.Acquire(ctx, "report", time.Minute)
+	require.NoError(t, err)
+	defer lease.Release(ctx)
+
+	server.FastForward(30 * time.Second)
+	assert.NoError(t, lease.Refresh(ctx))
+	assert.Equal(t, time.Minute, server.TTL("lock:report"))
+
+	// Another owner took the lock over: the lease can neither refresh nor release it.
+	require.NoError(t, server.Set("lock:report", "someone-else"))
+	assert.ErrorIs(t, lease.Refresh(ctx), lock.ErrLockNotHeld)
+	assert.ErrorIs(t, lease.Release(ctx), lock.ErrLockNotHeld)
+	assert.True(t, server.Exists("lock:report"))
+
+	select {
+	case <-lease.Lost():
+	default:
+		t.Fatal("lease not reported as lost")
+	}
+}
+
+func TestLease_AutoExtension(t *testing.T) {
+	locker, server := setUpLocker(t)
+	ctx := context.Background()
+
+	lease, err := locker.Acquire(ctx, "report", 150*time.Millisecond)
+	require.NoError(t, err)
+
+	server.FastForward(100 * time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return server.TTL("lock:report") == 150*time.Millisecond
+	}, time.Second, 5*time.Millisecond)
+
+	// Extension stops once the lock is taken over.
+	server.Del("lock:report")
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease not reported as lost")
+	}
+
+	assert.ErrorIs(t, lease.Release(ctx), lock.ErrLockNotHeld)
+}
+
+func TestLease_AutoExtension_RedisDown(t *testing.T) {
+	locker, server := setUpLocker(t)
+
+	lease, err := locker.Acquire(context.Background(), "report", 100*time.Millisecond)
+	require.NoError(t, err)
+	server.Close()
+
+	// Without Redis the lease cannot be extended, and is lost once its TTL has elapsed.
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lease not reported as lost")
+	}
+}
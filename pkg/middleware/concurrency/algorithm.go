@@ -0,0 +1,148 @@
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// Sample is the outcome of a request, used to adapt the concurrency limit.
+type Sample struct {
+	RTT      time.Duration // Time the request took
+	InFlight int           // Number of requests in flight when the request started, including it
+	Dropped  bool          // Whether the request failed because of overload, e.g. it timed out
+	Limit    int           // Current concurrency limit
+}
+
+// Algorithm adapts the concurrency limit from the outcome of requests.
+//
+// Update is called with the limiter's lock held, so an Algorithm needs no locking of its
+// own, but it keeps state and must not be shared between limiters.
+type Algorithm interface {
+	// Update returns the new concurrency limit after a request.
+	//
+	// Parameters:
+	// - sample (Sample): The outcome of the request and the current limit.
+	//
+	// Returns:
+	// - int: The new limit, before it is clamped to the limiter's bounds.
+	Update(sample Sample) int
+}
+
+// AIMDConfig configures the AIMD algorithm.
+type AIMDConfig struct {
+	Timeout      time.Duration // Latency above which a request counts as dropped; 1 second if zero
+	BackoffRatio float64       // Factor applied to the limit on a drop, in (0, 1); 0.9 if zero
+}
+
+// aimd is the additive increase, multiplicative decrease algorithm.
+type aimd struct {
+	// config holds the settings, with defaults applied.
+	config AIMDConfig
+}
+
+// NewAIMD returns the additive increase, multiplicative decrease algorithm, the same scheme
+// TCP uses for its congestion window: the limit grows by one after each successful request
+// made while at least half the limit was in use, and is multiplied by the backoff ratio after
+// each dropped or slow request. It reacts quickly to overload and needs no latency baseline.
+//
+// Parameters:
+// - config (AIMDConfig): The algorithm settings.
+//
+// Returns:
+// - Algorithm: The AIMD algorithm.
+func NewAIMD(config AIMDConfig) Algorithm {
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second
+	}
+	if config.BackoffRatio <= 0 || config.BackoffRatio >= 1 {
+		config.BackoffRatio = 0.9
+	}
+	return &aimd{config: config}
+}
+
+// Update applies a multiplicative decrease on drops and an additive increase otherwise.
+func (a *aimd) Update(sample Sample) int {
+	switch {
+	case sample.Dropped || sample.RTT > a.config.Timeout:
+		return int(float64(sample.Limit) * a.config.BackoffRatio)
+	case sample.InFlight*2 >= sample.Limit:
+		return sample.Limit + 1
+	default:
+		return sample.Limit
+	}
+}
+
+// GradientConfig configures the gradient algorithm.
+type GradientConfig struct {
+	Tolerance  float64 // Ratio of the current to the baseline latency tolerated before reducing the limit; 1.5 if zero
+	Smoothing  float64 // Weight of each new estimate in the limit, in (0, 1]; 0.2 if zero
+	LongWindow int     // Number of samples averaged into the baseline latency; 600 if zero
+}
+
+// gradient is the latency gradient algorithm.
+type gradient struct {
+	// config holds the settings, with defaults applied.
+	config GradientConfig
+	// limit is the unrounded limit estimate.
+	limit float64
+	// longRTT is the exponential moving average of the latency, in nanoseconds.
+	longRTT float64
+}
+
+// NewGradient returns the gradient algorithm, which compares the latency of each request
+// with a long-term baseline: while latency stays within the tolerance of the baseline, the
+// limit grows by its square root, the queue allowed to build up; as latency rises above it,
+// the limit shrinks in proportion. Unlike AIMD it detects overload before requests start
+// timing out, but needs traffic to learn the baseline.
+//
+// Parameters:
+// - config (GradientConfig): The algorithm settings.
+//
+// Returns:
+// - Algorithm: The gradient algorithm.
+func NewGradient(config GradientConfig) Algorithm {
+	if config.Tolerance <= 0 {
+		config.Tolerance = 1.5
+	}
+	if config.Smoothing <= 0 || config.Smoothing > 1 {
+		config.Smoothing = 0.2
+	}
+	if config.LongWindow <= 0 {
+		config.LongWindow = 600
+	}
+	return &gradient{config: config}
+}
+
+// Update moves the limit towards limit*gradient + sqrt(limit).
+func (g *gradient) Update(sample Sample) int {
+	if g.limit == 0 || int(math.Round(g.limit)) != sample.Limit {
+		// First sample, or the limit was clamped by the limiter.
+		g.limit = float64(sample.Limit)
+	}
+	rtt := float64(max(sample.RTT, time.Microsecond))
+
+	if g.longRTT == 0 {
+		g.longRTT = rtt
+	} else {
+		g.longRTT += (rtt - g.longRTT) * 2 / float64(g.config.LongWindow+1)
+	}
+	// After a sustained improvement, let the baseline catch up faster.
+	if g.longRTT/rtt > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// Do not grow while the limit is not in use, or it would grow without bound.
+	if sample.InFlight*2 < sample.Limit && !sample.Dropped {
+		return sample.Limit
+	}
+
+	ratio := g.config.Tolerance * g.longRTT / rtt
+	if sample.Dropped {
+		ratio = 0.5
+	}
+	ratio = max(0.5, min(1, ratio))
+
+	estimate := g.limit*ratio + math.Sqrt(g.limit)
+	g.limit = g.limit*(1-g.config.Smoothing) + estimate*g.config.Smoothing
+	return int(math.Round(g.limit))
+}
@@ -0,0 +1,56 @@
+package concurrency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/concurrency"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMD_Update(t *testing.T) {
+	algorithm := concurrency.NewAIMD(concurrency.AIMDConfig{Timeout: 100 * time.Millisecond, BackoffRatio: 0.5})
+
+	testCases := []struct {
+		name     string
+		sample   concurrency.Sample
+		expected int
+	}{
+		{name: "Increase when busy", sample: concurrency.Sample{RTT: time.Millisecond, InFlight: 5, Limit: 10}, expected: 11},
+		{name: "Hold when idle", sample: concurrency.Sample{RTT: time.Millisecond, InFlight: 4, Limit: 10}, expected: 10},
+		{name: "Decrease on drop", sample: concurrency.Sample{RTT: time.Millisecond, InFlight: 10, Dropped: true, Limit: 10}, expected: 5},
+		{name: "Decrease when slow", sample: concurrency.Sample{RTT: time.Second, InFlight: 10, Limit: 10}, expected: 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, algorithm.Update(tc.sample))
+		})
+	}
+}
+
+func TestGradient_Update(t *testing.T) {
+	algorithm := concurrency.NewGradient(concurrency.GradientConfig{LongWindow: 100})
+	limit := 20
+
+	// Steady latency under load lets the limit grow.
+	for i := 0; i < 20; i++ {
+		limit = algorithm.Update(concurrency.Sample{RTT: 10 * time.Millisecond, InFlight: limit, Limit: limit})
+	}
+	assert.Greater(t, limit, 20)
+	grown := limit
+
+	// Latency rising well above the baseline shrinks it.
+	for i := 0; i < 10; i++ {
+		limit = algorithm.Update(concurrency.Sample{RTT: 100 * time.Millisecond, InFlight: limit, Limit: limit})
+	}
+	assert.Less(t, limit, grown)
+	shrunk := limit
+
+	// An idle service keeps its limit.
+	assert.Equal(t, shrunk, algorithm.Update(concurrency.Sample{RTT: time.Millisecond, InFlight: 1, Limit: shrunk}))
+
+	// Drops shrink it regardless of latency.
+	assert.Less(t, algorithm.Update(concurrency.Sample{RTT: time.Millisecond, InFlight: 1, Dropped: true, Limit: shrunk}), shrunk)
+}
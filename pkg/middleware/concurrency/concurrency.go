@@ -0,0 +1,169 @@
+/*
+Package concurrency provides HTTP and gRPC middleware interceptors capping the number of
+requests in flight. Unlike a requests-per-second limit, the cap adapts to the service's
+health: a Limiter lowers it when latency rises or requests time out, e.g. when the database
+slows down, and raises it again as requests complete quickly, so that requests queue in
+clients rather than piling up inside the service.
+
+Requests beyond the cap are rejected with 503 Service Unavailable or codes.Unavailable,
+which clients can retry against another instance. The cap is adapted by an Algorithm:
+NewAIMD reacts to timeouts and slow requests, while NewGradient follows the latency trend.
+*/
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConcurrencyLimiterHTTP returns a middleware capping the number of HTTP requests in flight.
+//
+// If the limit is reached, the middleware logs a warning message (including the HTTP method,
+// path and current limit) and responds with a 503 Service Unavailable status code and a JSON
+// error message. Otherwise, it lets the request proceed and feeds its latency to the limiter;
+// requests answered with 503 or 504, or whose context deadline expired, count as dropped.
+//
+// Parameters:
+// - limiter (*Limiter): The concurrency limiter.
+// - logger (logger.Logger): The logger instance used to log rejected requests.
+//
+// Returns:
+// - gin.HandlerFunc: A `gin.HandlerFunc` that can be used as middleware in a Gin router.
+func ConcurrencyLimiterHTTP(limiter *Limiter, logger logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := limiter.Acquire()
+		if !ok {
+			logger.Warn("HTTP concurrency limit exceeded",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Int("limit", limiter.Limit()))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Too many concurrent requests"})
+			return
+		}
+		defer func() {
+			if errors.Is(c.Request.Context().Err(), context.Canceled) {
+				// The client went away: the latency says nothing about load.
+				token.Ignore()
+				return
+			}
+			code := c.Writer.Status()
+			token.Release(code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout ||
+				errors.Is(c.Request.Context().Err(), context.DeadlineExceeded))
+		}()
+
+		c.Next()
+	}
+}
+
+// ConcurrencyLimiterUnaryInterceptor returns a gRPC UnaryServerInterceptor capping the
+// number of unary calls in flight.
+//
+// If the limit is reached, the interceptor logs a warning message (including the full method
+// name and current limit) and returns a `codes.Unavailable` error. Otherwise, it lets the call
+// proceed and feeds its latency to the limiter; calls failing with DeadlineExceeded, as a
+// status or a raw context error, count as dropped.
+//
+// Parameters:
+// - limiter (*Limiter): The concurrency limiter.
+// - logger (logger.Logger): The logger instance used to log rejected calls.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A `grpc.UnaryServerInterceptor` that can be used with `grpc.Server`'s
+//     `UnaryInterceptor` option.
+func ConcurrencyLimiterUnaryInterceptor(limiter *Limiter, logger logger.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		token, ok := limiter.Acquire()
+		if !ok {
+			logger.Warn("gRPC unary concurrency limit exceeded",
+				zap.String("method", info.FullMethod),
+				zap.Int("limit", limiter.Limit()))
+			return nil, status.Error(codes.Unavailable, "too many concurrent requests")
+		}
+
+		var err error
+		completed := false
+		defer func() {
+			if !completed {
+				// The handler panicked: free the slot without feeding the limiter.
+				token.Ignore()
+				return
+			}
+			switch code(err) {
+			case codes.Canceled:
+				// The client went away: the latency says nothing about load.
+				token.Ignore()
+			case codes.DeadlineExceeded:
+				token.Release(true)
+			default:
+				token.Release(false)
+			}
+		}()
+
+		var resp interface{}
+		resp, err = handler(ctx, req)
+		completed = true
+		return resp, err
+	}
+}
+
+// ConcurrencyLimiterStreamInterceptor returns a gRPC StreamServerInterceptor capping the
+// number of streams in flight.
+//
+// If the limit is reached, the interceptor logs a warning message (including the full method
+// name and current limit) and returns a `codes.Unavailable` error. Otherwise, it lets the
+// stream proceed. Streams count against the limit while open, but their duration is not
+// fed to the limiter since it reflects the conversation rather than the load.
+//
+// Parameters:
+// - limiter (*Limiter): The concurrency limiter.
+// - logger (logger.Logger): The logger instance used to log rejected streams.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A `grpc.StreamServerInterceptor` that can be used with `grpc.Server`'s
+//     `StreamInterceptor` option.
+func ConcurrencyLimiterStreamInterceptor(limiter *Limiter, logger logger.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		token, ok := limiter.Acquire()
+		if !ok {
+			logger.Warn("gRPC stream concurrency limit exceeded",
+				zap.String("method", info.FullMethod),
+				zap.Int("limit", limiter.Limit()))
+			return status.Error(codes.Unavailable, "too many concurrent requests")
+		}
+		defer token.Ignore()
+
+		return handler(srv, ss)
+	}
+}
+
+// code returns the gRPC code of a handler error, including raw context errors such as those
+// returned through the timeout interceptor.
+func code(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return status.Code(err)
+	}
+}
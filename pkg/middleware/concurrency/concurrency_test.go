@@ -0,0 +1,184 @@
+package concurrency_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/concurrency"
+	"github.com/kmmania/er_commonlib/pkg/mocks/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testEnv struct {
+	ctrl       *gomock.Controller
+	mockLogger *mocks.MockLogger
+}
+
+func setUpTestEnv(t *testing.T) *testEnv {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any()).AnyTimes()
+
+	return &testEnv{
+		ctrl:       ctrl,
+		mockLogger: mockLogger,
+	}
+}
+
+func tearDownTestEnv(env *testEnv) {
+	env.ctrl.Finish()
+}
+
+func newLimiter(limit int) *concurrency.Limiter {
+	return concurrency.New(concurrency.NewAIMD(concurrency.AIMDConfig{}),
+		concurrency.Config{InitialLimit: limit, MaxLimit: limit}, zap.NewNop())
+}
+
+func TestConcurrencyLimiterHTTP(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	limiter := newLimiter(1)
+	started, unblock := make(chan struct{}), make(chan struct{})
+
+	router := gin.New()
+	router.Use(concurrency.ConcurrencyLimiterHTTP(limiter, env.mockLogger))
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-unblock
+		c.String(http.StatusOK, "OK")
+	})
+	router.GET("/fast", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}()
+	<-started
+
+	// The only slot is taken by the slow request.
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "Too many concurrent requests")
+
+	close(unblock)
+	wg.Wait()
+	assert.Equal(t, 0, limiter.InFlight())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimiterUnaryInterceptor(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	limiter := newLimiter(1)
+	interceptor := concurrency.ConcurrencyLimiterUnaryInterceptor(limiter, env.mockLogger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc.Service/Method"}
+
+	resp, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// The call in flight holds the only slot.
+		_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		return "success", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp)
+	assert.Equal(t, 0, limiter.InFlight())
+}
+
+func TestConcurrencyLimiterUnaryInterceptor_Adapts(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	testCases := []struct {
+		name        string
+		handlerErr  error
+		expectLimit int
+	}{
+		{name: "Success at full load raises the limit", handlerErr: nil, expectLimit: 3},
+		{name: "Other errors count as completed requests", handlerErr: status.Error(codes.NotFound, "not found"), expectLimit: 3},
+		{name: "Deadline exceeded lowers the limit", handlerErr: status.Error(codes.DeadlineExceeded, "timeout"), expectLimit: 1},
+		{name: "Cancelled calls are ignored", handlerErr: status.Error(codes.Canceled, "canceled"), expectLimit: 2},
+		{name: "Raw deadline exceeded lowers the limit", handlerErr: context.DeadlineExceeded, expectLimit: 1},
+		{name: "Raw cancellation is ignored", handlerErr: context.Canceled, expectLimit: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := concurrency.New(concurrency.NewAIMD(concurrency.AIMDConfig{BackoffRatio: 0.5}),
+				concurrency.Config{InitialLimit: 2}, zap.NewNop())
+			interceptor := concurrency.ConcurrencyLimiterUnaryInterceptor(limiter, env.mockLogger)
+
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc.Service/Method"},
+				func(ctx context.Context, req interface{}) (interface{}, error) { return nil, tc.handlerErr })
+			assert.Equal(t, status.Code(tc.handlerErr), status.Code(err))
+			assert.Equal(t, tc.expectLimit, limiter.Limit())
+			assert.Equal(t, 0, limiter.InFlight())
+		})
+	}
+}
+
+func TestConcurrencyLimiterUnaryInterceptor_Panic(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	limiter := newLimiter(1)
+	interceptor := concurrency.ConcurrencyLimiterUnaryInterceptor(limiter, env.mockLogger)
+
+	assert.Panics(t, func() {
+		_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc.Service/Method"},
+			func(ctx context.Context, req interface{}) (interface{}, error) { panic("boom") })
+	})
+	// The slot was freed, and the limit left unchanged.
+	assert.Equal(t, 0, limiter.InFlight())
+	assert.Equal(t, 1, limiter.Limit())
+}
+
+func TestConcurrencyLimiterStreamInterceptor(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	limiter := newLimiter(1)
+	interceptor := concurrency.ConcurrencyLimiterStreamInterceptor(limiter, env.mockLogger)
+	info := &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"}
+
+	err := interceptor(nil, &mockServerStream{ctx: context.Background()}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		// The open stream holds the only slot.
+		err := interceptor(nil, ss, info, func(srv interface{}, ss grpc.ServerStream) error { return nil })
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, limiter.InFlight())
+	assert.Equal(t, 1, limiter.Limit())
+}
+
+type mockServerStream struct {
+	ctx context.Context
+}
+
+func (m *mockServerStream) Context() context.Context     { return m.ctx }
+func (m *mockServerStream) SendMsg(interface{}) error    { return nil }
+func (m *mockServerStream) RecvMsg(interface{}) error    { return nil }
+func (m *mockServerStream) SetHeader(metadata.MD) error  { return nil }
+func (m *mockServerStream) SendHeader(metadata.MD) error { return nil }
+func (m *mockServerStream) SetTrailer(metadata.MD)       {}
@@ -0,0 +1,156 @@
+package concurrency
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Config bounds the concurrency limit.
+type Config struct {
+	InitialLimit int // Limit before any request completes; 20 if zero
+	MinLimit     int // Lowest limit the algorithm may set; 1 if zero
+	MaxLimit     int // Highest limit the algorithm may set; 1000 if zero
+}
+
+// Limiter caps the number of requests in flight, adapting the cap with an Algorithm from
+// the latency and outcome of completed requests. It is safe for concurrent use.
+type Limiter struct {
+	// algorithm adapts the limit.
+	algorithm Algorithm
+	// config holds the bounds of the limit, with defaults applied.
+	config Config
+	// logger provides structured logging for this Limiter's operations.
+	logger logger.Logger
+
+	// mu guards limit and inFlight, and serializes calls to the algorithm.
+	mu sync.Mutex
+	// limit is the current concurrency limit.
+	limit int
+	// inFlight is the number of requests in flight.
+	inFlight int
+}
+
+// New creates and returns a new Limiter.
+//
+// Parameters:
+// - algorithm (Algorithm): The algorithm adapting the limit, e.g. NewAIMD or NewGradient.
+// - config (Config): The bounds of the limit.
+// - logger (logger.Logger): A logger instance for logging limit changes.
+//
+// Returns:
+// - *Limiter: An initialized Limiter.
+func New(algorithm Algorithm, config Config, logger logger.Logger) *Limiter {
+	if config.MinLimit <= 0 {
+		config.MinLimit = 1
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = 1000
+	}
+	config.MaxLimit = max(config.MaxLimit, config.MinLimit)
+	if config.InitialLimit <= 0 {
+		config.InitialLimit = 20
+	}
+	config.InitialLimit = min(max(config.InitialLimit, config.MinLimit), config.MaxLimit)
+
+	return &Limiter{
+		algorithm: algorithm,
+		config:    config,
+		logger:    logger,
+		limit:     config.InitialLimit,
+	}
+}
+
+// Acquire takes a slot for a request if fewer requests than the limit are in flight.
+// The returned token must be released once the request completes.
+//
+// Returns:
+// - *Token: The token of the slot, or nil if the limit is reached.
+// - bool: Whether a slot was taken.
+func (l *Limiter) Acquire() (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= l.limit {
+		return nil, false
+	}
+	l.inFlight++
+	return &Token{limiter: l, start: time.Now(), inFlight: l.inFlight}, true
+}
+
+// Limit returns the current concurrency limit.
+//
+// Returns:
+// - int: The maximum number of requests allowed in flight.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// InFlight returns the number of requests in flight.
+//
+// Returns:
+// - int: The number of slots taken.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// release frees a slot and, unless ignored, adapts the limit from the request's outcome.
+func (l *Limiter) release(sample *Sample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if sample == nil {
+		return
+	}
+
+	sample.Limit = l.limit
+	limit := min(max(l.algorithm.Update(*sample), l.config.MinLimit), l.config.MaxLimit)
+	if limit != l.limit {
+		l.logger.Debug("Concurrency limit changed",
+			zap.Int("oldLimit", l.limit),
+			zap.Int("newLimit", limit),
+			zap.Duration("rtt", sample.RTT),
+			zap.Bool("dropped", sample.Dropped))
+		l.limit = limit
+	}
+}
+
+// Token is a slot taken by a request in flight.
+type Token struct {
+	// limiter is the Limiter the slot belongs to.
+	limiter *Limiter
+	// start is when the slot was taken.
+	start time.Time
+	// inFlight is the number of requests in flight when the slot was taken, including it.
+	inFlight int
+	// once guards releasing the slot.
+	once sync.Once
+}
+
+// Release frees the slot and feeds the request's latency and outcome to the algorithm.
+// Only the first call of Release or Ignore has an effect.
+//
+// Parameters:
+// - dropped (bool): Whether the request failed because of overload, e.g. it timed out.
+func (t *Token) Release(dropped bool) {
+	t.once.Do(func() {
+		t.limiter.release(&Sample{RTT: time.Since(t.start), InFlight: t.inFlight, Dropped: dropped})
+	})
+}
+
+// Ignore frees the slot without adapting the limit, for requests whose latency says
+// nothing about load, such as long-lived streams or requests cancelled by the client.
+// Only the first call of Release or Ignore has an effect.
+func (t *Token) Ignore() {
+	t.once.Do(func() {
+		t.limiter.release(nil)
+	})
+}
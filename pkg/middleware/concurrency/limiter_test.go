@@ -0,0 +1,67 @@
+package concurrency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/concurrency"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	limiter := concurrency.New(concurrency.NewAIMD(concurrency.AIMDConfig{}), concurrency.Config{InitialLimit: 2}, zap.NewNop())
+
+	first, ok := limiter.Acquire()
+	require.True(t, ok)
+	second, ok := limiter.Acquire()
+	require.True(t, ok)
+	assert.Equal(t, 2, limiter.InFlight())
+
+	_, ok = limiter.Acquire()
+	assert.False(t, ok)
+
+	// A successful request made at full load raises the limit.
+	first.Release(false)
+	first.Release(false)
+	assert.Equal(t, 1, limiter.InFlight())
+	assert.Equal(t, 3, limiter.Limit())
+
+	// Ignored requests free their slot without changing the limit.
+	second.Ignore()
+	assert.Equal(t, 0, limiter.InFlight())
+	assert.Equal(t, 3, limiter.Limit())
+}
+
+func TestLimiter_Bounds(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        concurrency.Config
+		expectInitial int
+		dropped       bool
+		expectLimit   int
+	}{
+		{name: "Defaults", config: concurrency.Config{}, expectInitial: 20, dropped: true, expectLimit: 18},
+		{name: "Initial limit clamped", config: concurrency.Config{InitialLimit: 50, MaxLimit: 10}, expectInitial: 10, dropped: false, expectLimit: 10},
+		{name: "Minimum limit", config: concurrency.Config{InitialLimit: 2, MinLimit: 2}, expectInitial: 2, dropped: true, expectLimit: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := concurrency.New(concurrency.NewAIMD(concurrency.AIMDConfig{Timeout: time.Second}), tc.config, zap.NewNop())
+			assert.Equal(t, tc.expectInitial, limiter.Limit())
+
+			// Fill the limiter so that the sample is taken at full load.
+			var tokens []*concurrency.Token
+			for i := 0; i < tc.expectInitial; i++ {
+				token, ok := limiter.Acquire()
+				require.True(t, ok)
+				tokens = append(tokens, token)
+			}
+			tokens[len(tokens)-1].Release(tc.dropped)
+			assert.Equal(t, tc.expectLimit, limiter.Limit())
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimiter
+
+import (
+	"context"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LimiterUnaryClientInterceptor returns a gRPC UnaryClientInterceptor that limits the rate
+// of outgoing unary gRPC calls using any Limiter, protecting a downstream service from
+// this client.
+//
+// If a call exceeds the limit, the interceptor logs a warning message (including the full
+// method name) and returns a `codes.ResourceExhausted` error carrying a RetryInfo detail,
+// without calling the server. If the limiter fails closed, it returns a `codes.Unavailable`
+// error. Key extractors are given the context of the outgoing call. When combined with the
+// retry interceptor of package retry, this interceptor should come after it, so that every
+// attempt is limited.
+//
+// Parameters:
+// - limiter (Limiter): The limiter deciding whether calls may proceed.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the key, e.g. ByMethod, or wait mode.
+//
+// Returns:
+//   - grpc.UnaryClientInterceptor: A `grpc.UnaryClientInterceptor` that can be used with the
+//     `grpc.WithUnaryInterceptor` dial option.
+func LimiterUnaryClientInterceptor(limiter Limiter, logger logger.Logger, opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		if err := o.allowCall(ctx, limiter, logger, method, "gRPC client unary rate limit exceeded"); err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
+
+// LimiterStreamClientInterceptor returns a gRPC StreamClientInterceptor that limits the
+// rate at which streams are opened using any Limiter.
+//
+// It otherwise behaves like LimiterUnaryClientInterceptor.
+//
+// Parameters:
+// - limiter (Limiter): The limiter deciding whether streams may be opened.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the key, e.g. ByMethod, or wait mode.
+//
+// Returns:
+//   - grpc.StreamClientInterceptor: A `grpc.StreamClientInterceptor` that can be used with the
+//     `grpc.WithStreamInterceptor` dial option.
+func LimiterStreamClientInterceptor(limiter Limiter, logger logger.Logger, opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if err := o.allowCall(ctx, limiter, logger, method, "gRPC client stream rate limit exceeded"); err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, callOpts...)
+	}
+}
+
+// allowCall admits an outgoing call, returning the error to fail it with if it may not
+// proceed.
+func (o *options) allowCall(ctx context.Context, limiter Limiter, logger logger.Logger, method, msg string) error {
+	result, err := o.allow(ctx, limiter, o.grpcKeyOf(ctx, method))
+	if result.Allowed {
+		return nil
+	}
+	if err != nil {
+		// The limiter failed closed
+		logger.Warn("gRPC client rate limiter unavailable", zap.Error(err), zap.String("method", method))
+		return status.Error(codes.Unavailable, "rate limiter unavailable")
+	}
+	// Log the rate limit exceed
+	logger.Warn(msg, zap.String("method", method))
+	// Return a ResourceExhausted error
+	return exhausted("client rate limit exceeded", result)
+}
@@ -0,0 +1,127 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// countingServer is a health server counting the calls it receives.
+type countingServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	calls atomic.Int32
+}
+
+func (s *countingServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.calls.Add(1)
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *countingServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc.ServerStreamingServer[grpc_health_v1.HealthCheckResponse]) error {
+	s.calls.Add(1)
+	return stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+}
+
+// dialHealth serves the server in process and returns a client connected to it through the options.
+func dialHealth(t *testing.T, server grpc_health_v1.HealthServer, opts ...grpc.DialOption) grpc_health_v1.HealthClient {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestLimiterUnaryClientInterceptor(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	server := &countingServer{}
+	client := dialHealth(t, server, grpc.WithUnaryInterceptor(
+		ratelimiter.LimiterUnaryClientInterceptor(ratelimiter.NewLocal(rate.Every(time.Minute), 1), env.mockLogger)))
+
+	ctx := context.Background()
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	// The second call is rejected without reaching the server.
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	info, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.InDelta(t, time.Minute, info.GetRetryDelay().AsDuration(), float64(time.Second))
+	assert.Equal(t, int32(1), server.calls.Load())
+}
+
+func TestLimiterStreamClientInterceptor(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	server := &countingServer{}
+	limiter := ratelimiter.NewRegistry(rate.Every(time.Minute), 1, 0)
+	client := dialHealth(t, server,
+		grpc.WithUnaryInterceptor(ratelimiter.LimiterUnaryClientInterceptor(limiter, env.mockLogger, ratelimiter.WithGRPCKey(ratelimiter.ByMethod()))),
+		grpc.WithStreamInterceptor(ratelimiter.LimiterStreamClientInterceptor(limiter, env.mockLogger, ratelimiter.WithGRPCKey(ratelimiter.ByMethod()))))
+
+	// Each method has its own limit.
+	ctx := context.Background()
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	_, err = client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, int32(2), server.calls.Load())
+}
+
+func TestLimiterClientInterceptor_BackendFailure(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	testCases := []struct {
+		name       string
+		failOpen   bool
+		expectCode codes.Code
+	}{
+		{name: "Fail open", failOpen: true, expectCode: codes.OK},
+		{name: "Fail closed", failOpen: false, expectCode: codes.Unavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := dialHealth(t, &countingServer{}, grpc.WithUnaryInterceptor(
+				ratelimiter.LimiterUnaryClientInterceptor(failingLimiter{failOpen: tc.failOpen}, env.mockLogger)))
+
+			_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			assert.Equal(t, tc.expectCode, status.Code(err))
+		})
+	}
+}
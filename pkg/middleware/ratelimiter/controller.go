@@ -0,0 +1,186 @@
+package ratelimiter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+var (
+	// ErrUnknownLimiter indicates that no limiter is registered under the name.
+	ErrUnknownLimiter = errors.New("ratelimiter: unknown limiter")
+
+	// ErrDuplicateLimiter indicates that a limiter is already registered under the name.
+	ErrDuplicateLimiter = errors.New("ratelimiter: duplicate limiter")
+)
+
+// NamedQuota is the quota of a limiter registered with a Controller.
+type NamedQuota struct {
+	Name  string `json:"name"` // Name of the limiter
+	Quota        // Current rate and burst
+}
+
+// Controller owns the limiters used by the middleware and changes their quotas at runtime,
+// e.g. from an admin endpoint, without redeploying. It is safe for concurrent use.
+type Controller struct {
+	// logger provides structured logging for quota changes.
+	logger logger.Logger
+
+	// mu guards limiters and serializes updates.
+	mu sync.Mutex
+	// limiters holds the registered limiters by name.
+	limiters map[string]Tunable
+}
+
+// NewController creates and returns a new Controller.
+//
+// Parameters:
+// - logger (logger.Logger): A logger instance for logging quota changes.
+//
+// Returns:
+// - *Controller: An initialized Controller without limiters.
+func NewController(logger logger.Logger) *Controller {
+	return &Controller{logger: logger, limiters: make(map[string]Tunable)}
+}
+
+// Register puts a limiter under the controller's management.
+//
+// Parameters:
+// - name (string): The name identifying the limiter in updates.
+// - limiter (Tunable): The limiter, e.g. a *Local, *Registry or *Redis.
+//
+// Returns:
+// - error: ErrDuplicateLimiter if the name is already taken.
+func (c *Controller) Register(name string, limiter Tunable) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.limiters[name]; ok {
+		return fmt.Errorf("%w: %q", ErrDuplicateLimiter, name)
+	}
+	c.limiters[name] = limiter
+	return nil
+}
+
+// Factory wraps a LimiterFactory so that the limiters it creates for a PolicyTable are
+// registered under their quota names: the rule patterns and "default".
+//
+// Parameters:
+// - factory (LimiterFactory): The factory creating the limiters; they must implement Tunable to be registered.
+//
+// Returns:
+// - LimiterFactory: The wrapping factory.
+func (c *Controller) Factory(factory LimiterFactory) LimiterFactory {
+	return func(name string, quota Quota) Limiter {
+		limiter := factory(name, quota)
+		if tunable, ok := limiter.(Tunable); ok {
+			if err := c.Register(name, tunable); err != nil {
+				c.logger.Warn("Rate limiter not registered", zap.String("name", name), zap.Error(err))
+			}
+		}
+		return limiter
+	}
+}
+
+// Quotas returns the current quota of every registered limiter, sorted by name.
+//
+// Returns:
+// - []NamedQuota: The quotas.
+func (c *Controller) Quotas() []NamedQuota {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	quotas := make([]NamedQuota, 0, len(c.limiters))
+	for name, limiter := range c.limiters {
+		quotas = append(quotas, NamedQuota{Name: name, Quota: limiter.Quota()})
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Name < quotas[j].Name })
+	return quotas
+}
+
+// Update changes the quota of a registered limiter. The change is applied atomically and
+// keeps the state of the limits, and is logged with the old and new values.
+//
+// Parameters:
+// - name (string): The name of the limiter.
+// - quota (Quota): The new rate and burst.
+//
+// Returns:
+// - error: ErrUnknownLimiter if no limiter has the name, or an error if the quota is invalid.
+func (c *Controller) Update(name string, quota Quota) error {
+	quota = quota.normalize()
+	if err := quota.validate(); err != nil {
+		return fmt.Errorf("ratelimiter: invalid quota for %q: %w", name, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limiter, ok := c.limiters[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownLimiter, name)
+	}
+	old := limiter.Quota()
+	limiter.SetQuota(quota)
+
+	c.logger.Info("Rate limit updated",
+		zap.String("name", name),
+		zap.Float64("oldRate", float64(old.Rate)),
+		zap.Int("oldBurst", old.Burst),
+		zap.Float64("newRate", float64(quota.Rate)),
+		zap.Int("newBurst", quota.Burst))
+	return nil
+}
+
+// MakeQuotasHandler returns a Gin handler listing the quotas of the registered limiters
+// as a JSON array of {"name", "rate", "burst"} objects.
+//
+// Returns:
+// - gin.HandlerFunc: An HTTP handler function, typically mounted on GET /admin/ratelimits.
+func (c *Controller) MakeQuotasHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, c.Quotas())
+	}
+}
+
+// MakeUpdateHandler returns a Gin handler changing the quota of a registered limiter from
+// a JSON {"name", "rate", "burst"} body. It responds with 200 and the new quota, 400 if the
+// body or the quota is invalid, or 404 if no limiter has the name.
+//
+// The endpoint changes the service's protection against overload: it must be mounted on an
+// admin router protected by authentication.
+//
+// Returns:
+// - gin.HandlerFunc: An HTTP handler function, typically mounted on PUT /admin/ratelimits.
+func (c *Controller) MakeUpdateHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var body struct {
+			Name  string   `json:"name" binding:"required"`
+			Rate  *float64 `json:"rate" binding:"required"`
+			Burst *int     `json:"burst" binding:"required"`
+		}
+		if err := ctx.ShouldBindJSON(&body); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		quota := Quota{Rate: rate.Limit(*body.Rate), Burst: *body.Burst}
+		if err := c.Update(body.Name, quota); err != nil {
+			httpStatus := http.StatusBadRequest
+			if errors.Is(err, ErrUnknownLimiter) {
+				httpStatus = http.StatusNotFound
+			}
+			ctx.JSON(httpStatus, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, NamedQuota{Name: body.Name, Quota: quota.normalize()})
+	}
+}
@@ -0,0 +1,130 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+func TestController_Update(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+	env.mockLogger.EXPECT().Info("Rate limit updated", gomock.Any()).Times(3)
+
+	controller := ratelimiter.NewController(env.mockLogger)
+	local := ratelimiter.NewLocal(rate.Every(time.Hour), 1)
+	registry := ratelimiter.NewRegistry(rate.Every(time.Hour), 1, 0)
+	redisLimiter, _ := setUpRedisLimiter(t, rate.Every(time.Hour), 1)
+	require.NoError(t, controller.Register("local", local))
+	require.NoError(t, controller.Register("registry", registry))
+	require.NoError(t, controller.Register("redis", redisLimiter))
+	assert.ErrorIs(t, controller.Register("local", local), ratelimiter.ErrDuplicateLimiter)
+
+	ctx := context.Background()
+	for _, limiter := range []ratelimiter.Limiter{local, registry, redisLimiter} {
+		result, err := limiter.Allow(ctx, "client")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	newQuota := ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 3}
+	for _, name := range []string{"local", "registry", "redis"} {
+		require.NoError(t, controller.Update(name, newQuota))
+	}
+	assert.ErrorIs(t, controller.Update("missing", newQuota), ratelimiter.ErrUnknownLimiter)
+	assert.Error(t, controller.Update("local", ratelimiter.Quota{Rate: 1}))
+
+	assert.Equal(t, []ratelimiter.NamedQuota{
+		{Name: "local", Quota: newQuota},
+		{Name: "redis", Quota: newQuota},
+		{Name: "registry", Quota: newQuota},
+	}, controller.Quotas())
+
+	// The limits are not reset: buckets keep their tokens, none being left, and Redis keeps
+	// the request already counted, two of the three requests remaining.
+	testCases := []struct {
+		limiter  ratelimiter.Limiter
+		expected []bool
+	}{
+		{limiter: local, expected: []bool{false}},
+		{limiter: registry, expected: []bool{false}},
+		{limiter: redisLimiter, expected: []bool{true, true, false}},
+	}
+	for _, tc := range testCases {
+		for _, expected := range tc.expected {
+			result, err := tc.limiter.Allow(ctx, "client")
+			require.NoError(t, err)
+			assert.Equal(t, expected, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+		}
+	}
+}
+
+func TestController_Factory(t *testing.T) {
+	controller := ratelimiter.NewController(zap.NewNop())
+	table, err := ratelimiter.NewPolicyTable(ratelimiter.Policies{
+		Default: &ratelimiter.Quota{Rate: 10, Burst: 10},
+		Rules:   []ratelimiter.Rule{{Pattern: "POST /orders", Quota: ratelimiter.Quota{Rate: 1, Burst: 1}}},
+	}, controller.Factory(ratelimiter.RegistryFactory(0)))
+	require.NoError(t, err)
+
+	assert.Equal(t, []ratelimiter.NamedQuota{
+		{Name: "POST /orders", Quota: ratelimiter.Quota{Rate: 1, Burst: 1}},
+		{Name: "default", Quota: ratelimiter.Quota{Rate: 10, Burst: 10}},
+	}, controller.Quotas())
+
+	require.NoError(t, controller.Update("POST /orders", ratelimiter.Quota{Rate: 5, Burst: 5}))
+	result, err := table.HTTPLimiter(http.MethodPost, "/orders").Allow(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Limit)
+}
+
+func TestController_Handlers(t *testing.T) {
+	controller := ratelimiter.NewController(zap.NewNop())
+	require.NoError(t, controller.Register("api", ratelimiter.NewLocal(10, 20)))
+
+	router := gin.New()
+	router.GET("/admin/ratelimits", controller.MakeQuotasHandler())
+	router.PUT("/admin/ratelimits", controller.MakeUpdateHandler())
+
+	testCases := []struct {
+		name         string
+		body         string
+		expectStatus int
+		expectBody   string
+	}{
+		{name: "Update", body: `{"name":"api","rate":5,"burst":5}`, expectStatus: http.StatusOK, expectBody: `{"name":"api","rate":5,"burst":5}`},
+		{name: "Unknown limiter", body: `{"name":"other","rate":5,"burst":5}`, expectStatus: http.StatusNotFound},
+		{name: "Missing burst", body: `{"name":"api","rate":5}`, expectStatus: http.StatusBadRequest},
+		{name: "Invalid quota", body: `{"name":"api","rate":5,"burst":0}`, expectStatus: http.StatusBadRequest},
+		{name: "Invalid JSON", body: `{`, expectStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/ratelimits", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.expectStatus, w.Code)
+			if tc.expectBody != "" {
+				assert.JSONEq(t, tc.expectBody, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/ratelimits", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"name":"api","rate":5,"burst":5}]`, w.Body.String())
+}
@@ -0,0 +1,81 @@
+package ratelimiter
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// HTTP headers describing the state of the limit, following the IETF RateLimit header
+// fields draft. gRPC trailers use the same names in lower case.
+const (
+	// HeaderLimit is the maximum number of requests allowed in a burst.
+	HeaderLimit = "RateLimit-Limit"
+	// HeaderRemaining is the number of requests still allowed right now.
+	HeaderRemaining = "RateLimit-Remaining"
+	// HeaderReset is the number of seconds until the limit is fully replenished.
+	HeaderReset = "RateLimit-Reset"
+	// HeaderRetryAfter is the number of seconds to wait before retrying a rejected request.
+	HeaderRetryAfter = "Retry-After"
+)
+
+// setHeaders sets the rate limit headers of an HTTP response, and Retry-After if the
+// request was rejected.
+func setHeaders(c *gin.Context, result Result) {
+	c.Header(HeaderLimit, strconv.Itoa(result.Limit))
+	c.Header(HeaderRemaining, strconv.Itoa(result.Remaining))
+	c.Header(HeaderReset, seconds(result.ResetAfter))
+	if !result.Allowed {
+		c.Header(HeaderRetryAfter, retrySeconds(result.RetryAfter))
+	}
+}
+
+// trailer returns the rate limit trailer of a gRPC call, including retry-after if the
+// call was rejected.
+func trailer(result Result) metadata.MD {
+	md := metadata.Pairs(
+		"ratelimit-limit", strconv.Itoa(result.Limit),
+		"ratelimit-remaining", strconv.Itoa(result.Remaining),
+		"ratelimit-reset", seconds(result.ResetAfter),
+	)
+	if !result.Allowed {
+		md.Set("retry-after", retrySeconds(result.RetryAfter))
+	}
+	return md
+}
+
+// setTrailer sets the rate limit trailer of a unary call. Setting it fails only outside a
+// gRPC server, e.g. when the interceptor is called directly, and is then skipped.
+func setTrailer(ctx context.Context, result Result) {
+	_ = grpc.SetTrailer(ctx, trailer(result))
+}
+
+// exhausted returns a ResourceExhausted error with the message and a RetryInfo detail
+// telling the client how long to wait before retrying.
+func exhausted(msg string, result Result) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	withInfo, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return withInfo.Err()
+}
+
+// seconds returns the duration in whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// retrySeconds returns the retry delay in whole seconds, rounded up and at least one so
+// that clients never retry immediately.
+func retrySeconds(d time.Duration) string {
+	return seconds(max(d, time.Second))
+}
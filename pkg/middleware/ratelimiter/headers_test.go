@@ -0,0 +1,142 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterHTTP_Headers(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	router := gin.New()
+	router.Use(ratelimiter.LimiterHTTP(ratelimiter.NewLocal(rate.Every(10*time.Second), 2), env.mockLogger))
+	router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+	testCases := []struct {
+		expectStatus     int
+		expectRemaining  string
+		expectReset      string
+		expectRetryAfter string
+	}{
+		{expectStatus: http.StatusOK, expectRemaining: "1", expectReset: "10"},
+		{expectStatus: http.StatusOK, expectRemaining: "0", expectReset: "20"},
+		{expectStatus: http.StatusTooManyRequests, expectRemaining: "0", expectReset: "20", expectRetryAfter: "10"},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, tc.expectStatus, w.Code)
+		assert.Equal(t, "2", w.Header().Get(ratelimiter.HeaderLimit))
+		assert.Equal(t, tc.expectRemaining, w.Header().Get(ratelimiter.HeaderRemaining))
+		assert.Equal(t, tc.expectReset, w.Header().Get(ratelimiter.HeaderReset))
+		assert.Equal(t, tc.expectRetryAfter, w.Header().Get(ratelimiter.HeaderRetryAfter))
+	}
+}
+
+func TestLimiterHTTP_Headers_BackendFailure(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	router := gin.New()
+	router.Use(ratelimiter.LimiterHTTP(failingLimiter{}, env.mockLogger))
+	router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t, w.Header().Get(ratelimiter.HeaderLimit))
+	assert.Empty(t, w.Header().Get(ratelimiter.HeaderRetryAfter))
+}
+
+// transportStream is a grpc.ServerTransportStream recording the trailer set by handlers.
+type transportStream struct {
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string                  { return "/svc.Service/Method" }
+func (s *transportStream) SetHeader(md metadata.MD) error  { return nil }
+func (s *transportStream) SendHeader(md metadata.MD) error { return nil }
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// trailerStream is a grpc.ServerStream recording its trailer.
+type trailerStream struct {
+	mockServerStream
+	trailer metadata.MD
+}
+
+func (s *trailerStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func TestLimiterUnaryInterceptor_Trailers(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterUnaryInterceptor(ratelimiter.NewLocal(rate.Every(10*time.Second), 1), env.mockLogger)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "success", nil }
+
+	stream := &transportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, stream.trailer.Get("ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, stream.trailer.Get("ratelimit-remaining"))
+	assert.Equal(t, []string{"10"}, stream.trailer.Get("ratelimit-reset"))
+	assert.Empty(t, stream.trailer.Get("retry-after"))
+
+	stream = &transportStream{}
+	ctx = grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"10"}, stream.trailer.Get("retry-after"))
+	assertRetryInfo(t, err, 10*time.Second)
+}
+
+func TestLimiterStreamInterceptor_Trailers(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterStreamInterceptor(ratelimiter.NewLocal(rate.Every(10*time.Second), 1), env.mockLogger)
+	handler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+
+	ss := &trailerStream{mockServerStream: mockServerStream{ctx: context.Background()}}
+	require.NoError(t, interceptor(nil, ss, &grpc.StreamServerInfo{}, handler))
+	assert.Equal(t, []string{"1"}, ss.trailer.Get("ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, ss.trailer.Get("ratelimit-remaining"))
+
+	ss = &trailerStream{mockServerStream: mockServerStream{ctx: context.Background()}}
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"10"}, ss.trailer.Get("retry-after"))
+	assertRetryInfo(t, err, 10*time.Second)
+}
+
+// assertRetryInfo asserts that the error carries a RetryInfo detail close to the expected delay.
+func assertRetryInfo(t *testing.T, err error, expected time.Duration) {
+	t.Helper()
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	info, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.InDelta(t, expected, info.GetRetryDelay().AsDuration(), float64(100*time.Millisecond))
+}
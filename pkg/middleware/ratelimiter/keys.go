@@ -0,0 +1,119 @@
+package ratelimiter
+
+import (
+	"context"
+	"net"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// HTTPKeyFunc extracts the key identifying the client of an HTTP request. Requests with
+// the same key share a limit; an empty key stands for requests whose client cannot be
+// identified, which all share one limit.
+type HTTPKeyFunc func(c *gin.Context) string
+
+// GRPCKeyFunc extracts the key identifying the client of a gRPC call. Calls with the same
+// key share a limit; an empty key stands for calls whose client cannot be identified, which
+// all share one limit.
+type GRPCKeyFunc func(ctx context.Context, fullMethod string) string
+
+// ByClientIP limits HTTP requests per client IP, as resolved by Gin from the remote address
+// and, for trusted proxies, the forwarding headers.
+//
+// Returns:
+// - HTTPKeyFunc: The key extractor.
+func ByClientIP() HTTPKeyFunc {
+	return func(c *gin.Context) string {
+		return c.ClientIP()
+	}
+}
+
+// ByHeader limits HTTP requests per value of a header, such as an API key.
+//
+// Parameters:
+// - name (string): The name of the header.
+//
+// Returns:
+// - HTTPKeyFunc: The key extractor.
+func ByHeader(name string) HTTPKeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// BySubject limits HTTP requests per authenticated subject, read from the Gin context key
+// under which an authentication middleware stored it as a string.
+//
+// Parameters:
+// - contextKey (string): The Gin context key holding the subject.
+//
+// Returns:
+// - HTTPKeyFunc: The key extractor.
+func BySubject(contextKey string) HTTPKeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetString(contextKey)
+	}
+}
+
+// ByMetadata limits gRPC calls per value of an incoming metadata key, such as an API key.
+// If the key has several values, the first one is used.
+//
+// Parameters:
+// - key (string): The metadata key.
+//
+// Returns:
+// - GRPCKeyFunc: The key extractor.
+func ByMetadata(key string) GRPCKeyFunc {
+	return func(ctx context.Context, _ string) string {
+		if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+}
+
+// ByPeer limits gRPC calls per peer host, ignoring the port so that every connection
+// from the same host shares the limit.
+//
+// Returns:
+// - GRPCKeyFunc: The key extractor.
+func ByPeer() GRPCKeyFunc {
+	return func(ctx context.Context, _ string) string {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return ""
+		}
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return host
+		}
+		return addr
+	}
+}
+
+// ByContextSubject limits gRPC calls per authenticated subject, as returned by a function
+// reading it from the context populated by an authentication interceptor.
+//
+// Parameters:
+// - subject (func(context.Context) string): Returns the subject of the call, or "" if unauthenticated.
+//
+// Returns:
+// - GRPCKeyFunc: The key extractor.
+func ByContextSubject(subject func(ctx context.Context) string) GRPCKeyFunc {
+	return func(ctx context.Context, _ string) string {
+		return subject(ctx)
+	}
+}
+
+// ByMethod limits gRPC calls per full method, e.g. to give each method of a downstream
+// service its own limit in a client interceptor.
+//
+// Returns:
+// - GRPCKeyFunc: The key extractor.
+func ByMethod() GRPCKeyFunc {
+	return func(_ context.Context, fullMethod string) string {
+		return fullMethod
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestHTTPKeyFuncs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keyFunc  ratelimiter.HTTPKeyFunc
+		setUp    func(c *gin.Context)
+		expected string
+	}{
+		{
+			name:     "Client IP",
+			keyFunc:  ratelimiter.ByClientIP(),
+			setUp:    func(c *gin.Context) { c.Request.RemoteAddr = "10.0.0.1:51234" },
+			expected: "10.0.0.1",
+		},
+		{
+			name:     "Header",
+			keyFunc:  ratelimiter.ByHeader("X-API-Key"),
+			setUp:    func(c *gin.Context) { c.Request.Header.Set("X-API-Key", "key-1") },
+			expected: "key-1",
+		},
+		{
+			name:     "Missing header",
+			keyFunc:  ratelimiter.ByHeader("X-API-Key"),
+			setUp:    func(c *gin.Context) {},
+			expected: "",
+		},
+		{
+			name:     "Subject",
+			keyFunc:  ratelimiter.BySubject("subject"),
+			setUp:    func(c *gin.Context) { c.Set("subject", "user-42") },
+			expected: "user-42",
+		},
+		{
+			name:     "Unauthenticated",
+			keyFunc:  ratelimiter.BySubject("subject"),
+			setUp:    func(c *gin.Context) {},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+			tc.setUp(c)
+			assert.Equal(t, tc.expected, tc.keyFunc(c))
+		})
+	}
+}
+
+type subjectKey struct{}
+
+func TestGRPCKeyFuncs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keyFunc  ratelimiter.GRPCKeyFunc
+		ctx      context.Context
+		expected string
+	}{
+		{
+			name:     "Metadata",
+			keyFunc:  ratelimiter.ByMetadata("x-api-key"),
+			ctx:      metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key-1", "x-api-key", "key-2")),
+			expected: "key-1",
+		},
+		{
+			name:     "Missing metadata",
+			keyFunc:  ratelimiter.ByMetadata("x-api-key"),
+			ctx:      context.Background(),
+			expected: "",
+		},
+		{
+			name:     "Peer",
+			keyFunc:  ratelimiter.ByPeer(),
+			ctx:      peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 51234}}),
+			expected: "10.0.0.1",
+		},
+		{
+			name:     "Missing peer",
+			keyFunc:  ratelimiter.ByPeer(),
+			ctx:      context.Background(),
+			expected: "",
+		},
+		{
+			name: "Subject",
+			keyFunc: ratelimiter.ByContextSubject(func(ctx context.Context) string {
+				subject, _ := ctx.Value(subjectKey{}).(string)
+				return subject
+			}),
+			ctx:      context.WithValue(context.Background(), subjectKey{}, "user-42"),
+			expected: "user-42",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.keyFunc(tc.ctx, "/svc.Service/Method"))
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Result is the outcome of a rate limiting decision and the state of the limit after it.
+type Result struct {
+	Allowed    bool          // Whether the request may proceed
+	Limit      int           // Maximum number of requests allowed in a burst
+	Remaining  int           // Number of requests still allowed right now
+	RetryAfter time.Duration // Time until a denied request would be allowed; zero if allowed
+	ResetAfter time.Duration // Time until the limit is fully replenished
+}
+
+// Limiter decides whether a request may proceed.
+//
+// Limiters are safe for concurrent use. A backend that cannot reach its store returns an
+// error together with a Result whose Allowed field holds its failure policy, so that callers
+// can tell a denied request from a limiter that failed closed.
+type Limiter interface {
+	// Allow consumes one request from the limit identified by key.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context of the request.
+	// - key (string): The key identifying the limit; empty for a global limit.
+	//
+	// Returns:
+	// - Result: The decision and the state of the limit.
+	// - error: An error if the backend failed; the Result then holds its failure policy.
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+// Reservation is a request admitted by a Reserver, possibly after a delay.
+type Reservation struct {
+	Result               // The decision and the state of the limit
+	Delay  time.Duration // Time the request must wait before proceeding, if allowed
+
+	// cancel gives the reserved request back to the limit; nil if the backend cannot.
+	cancel func()
+}
+
+// Cancel gives the reserved request back to the limit, when the request gives up waiting or
+// does not proceed after all, e.g. because another limit denied it. It is a no-op for
+// backends that cannot cancel reservations and for denied reservations.
+func (r Reservation) Cancel() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// Reserver is implemented by limiters able to admit a request in the near future rather
+// than only right now, which the middleware uses in wait mode.
+type Reserver interface {
+	// Reserve consumes one request from the limit identified by key if it can proceed
+	// within maxDelay. A denied reservation consumes nothing.
+	//
+	// Parameters:
+	// - ctx (context.Context): The context of the request.
+	// - key (string): The key identifying the limit; empty for a global limit.
+	// - maxDelay (time.Duration): The longest the request may wait.
+	//
+	// Returns:
+	// - Reservation: The decision, the state of the limit and the delay to wait.
+	// - error: An error if the backend failed; the Reservation then holds its failure policy.
+	Reserve(ctx context.Context, key string, maxDelay time.Duration) (Reservation, error)
+}
+
+// Tunable is implemented by limiters whose quota can be changed at runtime, as every
+// limiter of this package is. Changes are atomic: a decision sees either the old or the
+// new quota, never a mix of both. They keep the state of the limits rather than resetting
+// them: token buckets keep the tokens they hold, and Redis limits keep the requests they
+// counted, so a raised burst is available right away with Redis but refills with buckets.
+type Tunable interface {
+	// Quota returns the current quota.
+	//
+	// Returns:
+	// - Quota: The rate and burst.
+	Quota() Quota
+
+	// SetQuota changes the quota.
+	//
+	// Parameters:
+	// - quota (Quota): The new rate and burst.
+	SetQuota(quota Quota)
+}
+
+// Local is an in-process Limiter backed by a token bucket from golang.org/x/time/rate.
+// Every key shares the same bucket, and the limit applies to the current instance only.
+type Local struct {
+	// mu makes quota updates atomic: decisions hold it for reading, SetQuota for writing.
+	mu sync.RWMutex
+	// limiter is the token bucket.
+	limiter *rate.Limiter
+}
+
+// NewLocal creates and returns a new Local limiter.
+//
+// Parameters:
+// - limit (rate.Limit): The number of requests allowed per second.
+// - burst (int): The maximum number of requests allowed at once.
+//
+// Returns:
+// - *Local: An initialized Local limiter.
+func NewLocal(limit rate.Limit, burst int) *Local {
+	return FromRate(rate.NewLimiter(limit, burst))
+}
+
+// FromRate returns a Local limiter using an existing token bucket.
+//
+// Parameters:
+// - rl (*rate.Limiter): The token bucket.
+//
+// Returns:
+// - *Local: A Local limiter sharing the token bucket.
+func FromRate(rl *rate.Limiter) *Local {
+	return &Local{limiter: rl}
+}
+
+// Allow consumes one token from the bucket. It ignores the key and never fails.
+func (l *Local) Allow(_ context.Context, _ string) (Result, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := time.Now()
+	allowed := l.limiter.AllowN(now, 1)
+	return bucketResult(l.limiter, now, allowed), nil
+}
+
+// Reserve reserves one token from the bucket if it is available within maxDelay.
+// It ignores the key and never fails.
+func (l *Local) Reserve(_ context.Context, _ string, maxDelay time.Duration) (Reservation, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return reserveBucket(l.limiter, maxDelay), nil
+}
+
+// Quota returns the rate and burst of the bucket.
+func (l *Local) Quota() Quota {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return Quota{Rate: l.limiter.Limit(), Burst: l.limiter.Burst()}
+}
+
+// SetQuota changes the rate and burst of the bucket, keeping the tokens it holds.
+func (l *Local) SetQuota(quota Quota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	setBucketQuota(l.limiter, time.Now(), quota)
+}
+
+// setBucketQuota changes the rate and burst of a bucket, keeping the tokens it holds.
+func setBucketQuota(rl *rate.Limiter, now time.Time, quota Quota) {
+	rl.SetLimitAt(now, quota.Rate)
+	rl.SetBurstAt(now, quota.Burst)
+}
+
+// reserveBucket reserves one token from the bucket if it is available within maxDelay.
+func reserveBucket(rl *rate.Limiter, maxDelay time.Duration) Reservation {
+	now := time.Now()
+	r := rl.ReserveN(now, 1)
+	if !r.OK() {
+		return Reservation{Result: bucketResult(rl, now, false)}
+	}
+	delay := r.DelayFrom(now)
+	if delay > maxDelay {
+		r.CancelAt(now)
+		return Reservation{Result: bucketResult(rl, now, false)}
+	}
+	// Cancelling at the time to act at the latest also gives back a token already spent
+	act := now.Add(delay)
+	cancel := func() {
+		t := time.Now()
+		if act.Before(t) {
+			t = act
+		}
+		r.CancelAt(t)
+	}
+	return Reservation{Result: bucketResult(rl, now, true), Delay: delay, cancel: cancel}
+}
+
+// bucketResult describes the state of a token bucket after a decision.
+func bucketResult(rl *rate.Limiter, now time.Time, allowed bool) Result {
+	limit, burst := rl.Limit(), rl.Burst()
+	tokens := rl.TokensAt(now)
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      burst,
+		Remaining:  max(int(math.Floor(tokens)), 0),
+		ResetAfter: refillDuration(float64(burst)-tokens, limit),
+	}
+	if !allowed {
+		result.RetryAfter = refillDuration(1-tokens, limit)
+	}
+	return result
+}
+
+// refillDuration returns the time the bucket takes to gain the given number of tokens.
+func refillDuration(tokens float64, limit rate.Limit) time.Duration {
+	if tokens <= 0 || limit == rate.Inf || limit <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / float64(limit) * float64(time.Second)))
+}
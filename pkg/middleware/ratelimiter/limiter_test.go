@@ -0,0 +1,115 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+	"github.com/kmmania/er_commonlib/pkg/mocks/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLocal_Allow(t *testing.T) {
+	limiter := ratelimiter.NewLocal(10, 2)
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Zero(t, result.RetryAfter)
+	assert.InDelta(t, 100*time.Millisecond, result.ResetAfter, float64(10*time.Millisecond))
+
+	result, err = limiter.Allow(ctx, "other key")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// Every key shares the bucket.
+	result, err = limiter.Allow(ctx, "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.InDelta(t, 100*time.Millisecond, result.RetryAfter, float64(10*time.Millisecond))
+	assert.InDelta(t, 200*time.Millisecond, result.ResetAfter, float64(10*time.Millisecond))
+}
+
+// failingLimiter is a Limiter whose backend always fails, with the given failure policy.
+type failingLimiter struct {
+	failOpen bool
+}
+
+func (f failingLimiter) Allow(context.Context, string) (ratelimiter.Result, error) {
+	return ratelimiter.Result{Allowed: f.failOpen}, assert.AnError
+}
+
+func TestLimiter_BackendFailure(t *testing.T) {
+	testCases := []struct {
+		name        string
+		failOpen    bool
+		expectHTTP  int
+		expectCode  codes.Code
+		expectWarns int
+	}{
+		{name: "Fail open", failOpen: true, expectHTTP: http.StatusOK, expectCode: codes.OK},
+		{name: "Fail closed", failOpen: false, expectHTTP: http.StatusServiceUnavailable, expectCode: codes.Unavailable, expectWarns: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockLogger := mocks.NewMockLogger(ctrl)
+			// Failing closed is logged with the limiter error, failing open is not logged.
+			mockLogger.EXPECT().Warn("HTTP rate limiter unavailable", gomock.Any()).Times(tc.expectWarns)
+			mockLogger.EXPECT().Warn("gRPC unary rate limiter unavailable", gomock.Any()).Times(tc.expectWarns)
+			mockLogger.EXPECT().Warn("gRPC stream rate limiter unavailable", gomock.Any()).Times(tc.expectWarns)
+
+			limiter := failingLimiter{failOpen: tc.failOpen}
+
+			router := gin.New()
+			router.Use(ratelimiter.LimiterHTTP(limiter, mockLogger))
+			router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+			assert.Equal(t, tc.expectHTTP, w.Code)
+
+			unary := ratelimiter.LimiterUnaryInterceptor(limiter, mockLogger)
+			_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, req interface{}) (interface{}, error) { return "success", nil })
+			assert.Equal(t, tc.expectCode, status.Code(err))
+
+			stream := ratelimiter.LimiterStreamInterceptor(limiter, mockLogger)
+			err = stream(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{},
+				func(srv interface{}, ss grpc.ServerStream) error { return nil })
+			assert.Equal(t, tc.expectCode, status.Code(err))
+		})
+	}
+}
+
+func TestLimiterHTTP_RateLimited(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	router := gin.New()
+	router.Use(ratelimiter.LimiterHTTP(ratelimiter.NewLocal(rate.Every(time.Hour), 1), env.mockLogger))
+	router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+	for _, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.Equal(t, expected, w.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MessageLimits limits the messages of gRPC streams, which the stream interceptors otherwise
+// only limit when they open.
+type MessageLimits struct {
+	// PerStream is the quota of the messages of each stream; nil for no per-stream limit.
+	PerStream *Quota
+	// Global limits the messages of all streams together, per client key when the
+	// interceptor has one; nil for no global limit.
+	Global Limiter
+	// MaxWait is the longest a message over the limit waits for its turn, bounded by the
+	// stream's deadline; zero fails the stream right away.
+	MaxWait time.Duration
+}
+
+// WithMessageLimits limits each message received and sent on streams, in addition to the
+// opening of the streams. A message over the limit waits for its turn up to MaxWait, which
+// slows the peer down through flow control, and otherwise fails the stream with
+// `codes.ResourceExhausted`. Streams that are not limited when they open, such as exempt
+// ones, are not limited per message either.
+//
+// The per-stream quota is validated like the quotas of a PolicyTable; as the option is
+// given when the interceptor is built, an invalid quota panics then.
+//
+// Parameters:
+// - limits (MessageLimits): The per-stream and global message limits.
+//
+// Returns:
+// - Option: An option for the stream interceptors; the other middleware and interceptors ignore it.
+func WithMessageLimits(limits MessageLimits) Option {
+	if limits.PerStream != nil {
+		quota := limits.PerStream.normalize()
+		if err := quota.validate(); err != nil {
+			panic(fmt.Sprintf("ratelimiter: invalid per-stream message quota: %v", err))
+		}
+		limits.PerStream = &quota
+	}
+	return func(o *options) {
+		o.messages = &limits
+	}
+}
+
+// limitedServerStream wraps grpc.ServerStream to limit the rate of its messages.
+//
+// limitedServerStream embeds the original grpc.ServerStream and overrides the RecvMsg and
+// SendMsg methods to admit each message through the limiters.
+type limitedServerStream struct {
+	grpc.ServerStream
+	// limiters are the per-stream and global limiters.
+	limiters []Limiter
+	// key is the client key of the stream.
+	key string
+	// wait holds the wait mode of the messages.
+	wait *options
+	// logger provides structured logging for rejected messages.
+	logger logger.Logger
+	// method is the full method of the stream.
+	method string
+}
+
+// limitMessages wraps the stream to apply the message limits, or returns it as is if
+// there are none.
+func (o *options) limitMessages(ss grpc.ServerStream, logger logger.Logger, method string) grpc.ServerStream {
+	if o.messages == nil {
+		return ss
+	}
+	var limiters []Limiter
+	if quota := o.messages.PerStream; quota != nil {
+		limiters = append(limiters, NewLocal(quota.Rate, quota.Burst))
+	}
+	if o.messages.Global != nil {
+		limiters = append(limiters, o.messages.Global)
+	}
+	if len(limiters) == 0 {
+		return ss
+	}
+	return &limitedServerStream{
+		ServerStream: ss,
+		limiters:     limiters,
+		key:          o.grpcKeyOf(ss.Context(), method),
+		wait:         &options{maxDelay: o.messages.MaxWait},
+		logger:       logger,
+		method:       method,
+	}
+}
+
+// RecvMsg receives a message and admits it through the limiters. Only messages count: the
+// end of the stream, or any other receive error, is returned as is.
+func (s *limitedServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.admit("received")
+}
+
+// SendMsg sends a message once the limiters admit it.
+func (s *limitedServerStream) SendMsg(m interface{}) error {
+	if err := s.admit("sent"); err != nil {
+		return err
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+// admit admits a message through every limiter, returning the error to fail the stream
+// with if it may not proceed. A message denied by a limiter is given back to the limiters
+// that admitted it before, so that undelivered messages do not count against them.
+func (s *limitedServerStream) admit(direction string) error {
+	ctx := s.Context()
+	admitted := make([]Reservation, 0, len(s.limiters))
+	for _, limiter := range s.limiters {
+		reservation, err := s.wait.reserve(ctx, limiter, s.key)
+		if reservation.Allowed {
+			admitted = append(admitted, reservation)
+			continue
+		}
+		for _, r := range admitted {
+			r.Cancel()
+		}
+		result := reservation.Result
+		if err != nil {
+			// The limiter failed closed
+			s.logger.Warn("gRPC stream message rate limiter unavailable",
+				zap.Error(err),
+				zap.String("method", s.method),
+				zap.String("direction", direction))
+			return status.Error(codes.Unavailable, "rate limiter unavailable")
+		}
+		// Log the rate limit exceed
+		s.logger.Warn("gRPC stream message rate limit exceeded",
+			zap.String("method", s.method),
+			zap.String("direction", direction))
+		// Return a ResourceExhausted error
+		return exhausted("too many messages: rate limiting on stream", result)
+	}
+	return nil
+}
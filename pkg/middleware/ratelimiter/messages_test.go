@@ -0,0 +1,260 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// exchange returns a stream handler receiving and sending messages alternately, recording
+// the error of each message until one fails.
+func exchange(messages int, errs *[]error) grpc.StreamHandler {
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		for i := 0; i < messages; i++ {
+			var err error
+			if i%2 == 0 {
+				err = ss.RecvMsg(nil)
+			} else {
+				err = ss.SendMsg(nil)
+			}
+			*errs = append(*errs, err)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestWithMessageLimits(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	testCases := []struct {
+		name         string
+		limits       func() ratelimiter.MessageLimits
+		streams      []int
+		expectCodes  []codes.Code
+		expectLength []int
+	}{
+		{
+			name: "Per-stream limit",
+			limits: func() ratelimiter.MessageLimits {
+				return ratelimiter.MessageLimits{PerStream: &ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 2}}
+			},
+			streams:      []int{3, 2},
+			expectCodes:  []codes.Code{codes.ResourceExhausted, codes.OK},
+			expectLength: []int{3, 2},
+		},
+		{
+			name: "Global limit shared by the streams",
+			limits: func() ratelimiter.MessageLimits {
+				return ratelimiter.MessageLimits{Global: ratelimiter.NewLocal(rate.Every(time.Hour), 3)}
+			},
+			streams:      []int{2, 2},
+			expectCodes:  []codes.Code{codes.OK, codes.ResourceExhausted},
+			expectLength: []int{2, 2},
+		},
+		{
+			name: "Both limits",
+			limits: func() ratelimiter.MessageLimits {
+				return ratelimiter.MessageLimits{
+					PerStream: &ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 2},
+					Global:    ratelimiter.NewLocal(rate.Every(time.Hour), 3),
+				}
+			},
+			streams:      []int{3, 2},
+			expectCodes:  []codes.Code{codes.ResourceExhausted, codes.ResourceExhausted},
+			expectLength: []int{3, 2},
+		},
+		{
+			name: "Global limiter failing closed",
+			limits: func() ratelimiter.MessageLimits {
+				return ratelimiter.MessageLimits{Global: failingLimiter{}}
+			},
+			streams:      []int{1},
+			expectCodes:  []codes.Code{codes.Unavailable},
+			expectLength: []int{1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			interceptor := ratelimiter.LimiterStreamInterceptor(ratelimiter.NewLocal(rate.Inf, 0), env.mockLogger,
+				ratelimiter.WithMessageLimits(tc.limits()))
+
+			for i, messages := range tc.streams {
+				var errs []error
+				err := interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"},
+					exchange(messages, &errs))
+				assert.Equal(t, tc.expectCodes[i], status.Code(err))
+				assert.Len(t, errs, tc.expectLength[i])
+			}
+		})
+	}
+}
+
+func TestWithMessageLimits_Wait(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterStreamInterceptor(ratelimiter.NewLocal(rate.Inf, 0), env.mockLogger,
+		ratelimiter.WithMessageLimits(ratelimiter.MessageLimits{
+			PerStream: &ratelimiter.Quota{Rate: 20, Burst: 1},
+			MaxWait:   time.Second,
+		}))
+
+	// The messages over the limit wait for their turn, one every 50ms.
+	var errs []error
+	start := time.Now()
+	err := interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"},
+		exchange(3, &errs))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestWithMessageLimits_Exempt(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	table, err := ratelimiter.NewPolicyTable(ratelimiter.Policies{
+		Default: &ratelimiter.Quota{Rate: rate.Inf},
+		Exempt:  []string{"/grpc.health.v1.Health/*"},
+	}, ratelimiter.LocalFactory())
+	require.NoError(t, err)
+	interceptor := ratelimiter.PolicyStreamInterceptor(table, env.mockLogger,
+		ratelimiter.WithMessageLimits(ratelimiter.MessageLimits{PerStream: &ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 1}}))
+
+	testCases := []struct {
+		method     string
+		expectCode codes.Code
+	}{
+		{method: "/grpc.health.v1.Health/Watch", expectCode: codes.OK},
+		{method: "/svc.Service/Stream", expectCode: codes.ResourceExhausted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method, func(t *testing.T) {
+			var errs []error
+			err := interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: tc.method},
+				exchange(3, &errs))
+			assert.Equal(t, tc.expectCode, status.Code(err))
+		})
+	}
+}
+
+// halfClosedStream is a grpc.ServerStream whose client sends a number of messages, then
+// closes its side of the stream.
+type halfClosedStream struct {
+	mockServerStream
+	messages int
+}
+
+func (s *halfClosedStream) RecvMsg(interface{}) error {
+	if s.messages == 0 {
+		return io.EOF
+	}
+	s.messages--
+	return nil
+}
+
+func TestWithMessageLimits_HalfClose(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterStreamInterceptor(ratelimiter.NewLocal(rate.Inf, 0), env.mockLogger,
+		ratelimiter.WithMessageLimits(ratelimiter.MessageLimits{PerStream: &ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 2}}))
+
+	// The client sends exactly the burst, then closes cleanly: the end of the stream is not a message.
+	received := 0
+	err := interceptor(nil, &halfClosedStream{mockServerStream: mockServerStream{ctx: context.Background()}, messages: 2},
+		&grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			for {
+				err := ss.RecvMsg(nil)
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				received++
+			}
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, received)
+}
+
+// denyingLimiter is a Limiter denying its first requests, then allowing every request.
+type denyingLimiter struct {
+	denials int
+}
+
+func (l *denyingLimiter) Allow(context.Context, string) (ratelimiter.Result, error) {
+	if l.denials > 0 {
+		l.denials--
+		return ratelimiter.Result{}, nil
+	}
+	return ratelimiter.Result{Allowed: true}, nil
+}
+
+func TestWithMessageLimits_GlobalDenialRefunds(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterStreamInterceptor(ratelimiter.NewLocal(rate.Inf, 0), env.mockLogger,
+		ratelimiter.WithMessageLimits(ratelimiter.MessageLimits{
+			PerStream: &ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 2},
+			Global:    &denyingLimiter{denials: 2},
+		}))
+
+	// The messages denied by the global limit are not delivered, so they do not use up the
+	// per-stream limit.
+	var errs []error
+	_ = interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"},
+		func(srv interface{}, ss grpc.ServerStream) error {
+			for i := 0; i < 4; i++ {
+				errs = append(errs, ss.RecvMsg(nil))
+			}
+			return nil
+		})
+	require.Len(t, errs, 4)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(errs[0]))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(errs[1]))
+	assert.NoError(t, errs[2])
+	assert.NoError(t, errs[3])
+}
+
+func TestWithMessageLimits_InvalidQuota(t *testing.T) {
+	testCases := []struct {
+		name  string
+		quota ratelimiter.Quota
+	}{
+		{name: "Negative rate", quota: ratelimiter.Quota{Rate: -1, Burst: 1}},
+		{name: "Negative burst", quota: ratelimiter.Quota{Rate: 1, Burst: -1}},
+		{name: "Zero burst", quota: ratelimiter.Quota{Rate: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Panics(t, func() {
+				ratelimiter.WithMessageLimits(ratelimiter.MessageLimits{PerStream: &tc.quota})
+			})
+		})
+	}
+
+	assert.NotPanics(t, func() {
+		ratelimiter.WithMessageLimits(ratelimiter.MessageLimits{PerStream: &ratelimiter.Quota{Rate: 1, Burst: 1}})
+	})
+}
@@ -0,0 +1,297 @@
+package ratelimiter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/kmmania/er_commonlib/internal/glob"
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPolicyName names the limiter of the default quota.
+const defaultPolicyName = "default"
+
+// Quota is a rate limit: a token bucket refilled at Rate requests per second and holding
+// up to Burst requests.
+type Quota struct {
+	Rate  rate.Limit `yaml:"rate" json:"rate"`   // Requests allowed per second; .inf for no limit
+	Burst int        `yaml:"burst" json:"burst"` // Maximum number of requests allowed at once
+}
+
+// Rule applies a quota to the routes or gRPC methods matching a pattern.
+//
+// A pattern is a glob where '*' matches any sequence of characters and '?' exactly one.
+// It is matched against the HTTP request path or the gRPC full method, e.g.
+// "/api/v1/orders*" or "/orders.OrderService/*". It may be prefixed with an HTTP method
+// and a space, e.g. "POST /api/v1/orders*", to match only requests with that method;
+// such patterns never match gRPC calls.
+type Rule struct {
+	Pattern string `yaml:"pattern"` // Route or method pattern
+	Quota   `yaml:",inline"`
+}
+
+// Policies configures a PolicyTable.
+//
+// Requests matching an exemption pattern are never limited. Other requests get the quota
+// of the first matching rule, or the default quota if none matches. Every rule has its own
+// limit, shared by all the requests it matches.
+type Policies struct {
+	Default *Quota   `yaml:"default"` // Quota of requests matching no rule; nil to not limit them
+	Rules   []Rule   `yaml:"rules"`   // Per-pattern quotas, checked in order
+	Exempt  []string `yaml:"exempt"`  // Patterns of requests never limited, e.g. health checks
+}
+
+// LimiterFactory creates the Limiter enforcing a quota of a PolicyTable.
+//
+// Parameters:
+// - name (string): The name of the quota, unique in the table: the rule's pattern, or "default".
+// - quota (Quota): The quota to enforce.
+//
+// Returns:
+// - Limiter: The limiter enforcing the quota.
+type LimiterFactory func(name string, quota Quota) Limiter
+
+// LocalFactory returns a LimiterFactory creating Local limiters, for global limits
+// enforced by each instance.
+//
+// Returns:
+// - LimiterFactory: The factory.
+func LocalFactory() LimiterFactory {
+	return func(_ string, quota Quota) Limiter {
+		return NewLocal(quota.Rate, quota.Burst)
+	}
+}
+
+// RegistryFactory returns a LimiterFactory creating Registry limiters, for per-key limits
+// enforced by each instance.
+//
+// Parameters:
+// - idleTimeout (time.Duration): The idle timeout of the per-key buckets; DefaultIdleTimeout if zero.
+// - opts (...RegistryOption): Optional settings of each Registry, such as the maximum number of buckets.
+//
+// Returns:
+// - LimiterFactory: The factory.
+func RegistryFactory(idleTimeout time.Duration, opts ...RegistryOption) LimiterFactory {
+	return func(_ string, quota Quota) Limiter {
+		return NewRegistry(quota.Rate, quota.Burst, idleTimeout, opts...)
+	}
+}
+
+// RedisFactory returns a LimiterFactory creating Redis limiters, for limits shared across
+// instances. The keys of each quota are prefixed with the key prefix followed by the
+// quota's name and a colon.
+//
+// Parameters:
+// - client (redis.UniversalClient): The Redis client holding the limits.
+// - logger (logger.Logger): A logger instance for logging Redis errors.
+// - opts (...RedisOption): Optional settings applied to every limiter.
+//
+// Returns:
+// - LimiterFactory: The factory.
+func RedisFactory(client redis.UniversalClient, logger logger.Logger, opts ...RedisOption) LimiterFactory {
+	return func(name string, quota Quota) Limiter {
+		r := NewRedis(client, logger, quota.Rate, quota.Burst, opts...)
+		r.prefix += name + ":"
+		return r
+	}
+}
+
+// LoadPolicies parses policies from YAML, for example:
+//
+//	default: {rate: 100, burst: 200}
+//	rules:
+//	  - pattern: "POST /api/v1/orders*"
+//	    rate: 5
+//	    burst: 10
+//	  - pattern: "/orders.OrderService/Create*"
+//	    rate: 5
+//	    burst: 10
+//	exempt:
+//	  - "/health*"
+//	  - "/grpc.health.v1.Health/*"
+//
+// Parameters:
+// - data ([]byte): The YAML document.
+//
+// Returns:
+// - Policies: The parsed policies.
+// - error: An error if the document is invalid or has unknown fields.
+func LoadPolicies(data []byte) (Policies, error) {
+	var policies Policies
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policies); err != nil && !errors.Is(err, io.EOF) {
+		return Policies{}, fmt.Errorf("ratelimiter: invalid policies: %w", err)
+	}
+
+	for i := range policies.Rules {
+		policies.Rules[i].Quota = policies.Rules[i].Quota.normalize()
+	}
+	if policies.Default != nil {
+		quota := policies.Default.normalize()
+		policies.Default = &quota
+	}
+	return policies, nil
+}
+
+// PolicyTable resolves the limiter applying to each route or gRPC method.
+// It is immutable once built and safe for concurrent use.
+type PolicyTable struct {
+	// rules holds the compiled rules, in order.
+	rules []policy
+	// exempt holds the compiled exemption patterns.
+	exempt []pattern
+	// fallback enforces the default quota; nil if requests matching no rule are not limited.
+	fallback Limiter
+}
+
+// policy is a compiled rule.
+type policy struct {
+	pattern
+	// limiter enforces the rule's quota.
+	limiter Limiter
+}
+
+// pattern is a parsed route or method pattern.
+type pattern struct {
+	// method is the HTTP method the pattern is restricted to; empty for any method and gRPC.
+	method string
+	// glob is matched against the path or full method.
+	glob string
+}
+
+// NewPolicyTable validates the policies and creates the limiter of each quota.
+//
+// Parameters:
+// - policies (Policies): The policies.
+// - factory (LimiterFactory): Creates the limiter of each quota.
+//
+// Returns:
+// - *PolicyTable: The policy table.
+// - error: An error if a pattern or a quota is invalid.
+func NewPolicyTable(policies Policies, factory LimiterFactory) (*PolicyTable, error) {
+	table := &PolicyTable{}
+
+	for _, raw := range policies.Exempt {
+		p, err := parsePattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		table.exempt = append(table.exempt, p)
+	}
+
+	seen := make(map[string]bool, len(policies.Rules))
+	for _, rule := range policies.Rules {
+		p, err := parsePattern(rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		if seen[rule.Pattern] {
+			return nil, fmt.Errorf("ratelimiter: duplicate rule pattern %q", rule.Pattern)
+		}
+		seen[rule.Pattern] = true
+		quota := rule.Quota.normalize()
+		if err := quota.validate(); err != nil {
+			return nil, fmt.Errorf("ratelimiter: rule %q: %w", rule.Pattern, err)
+		}
+		table.rules = append(table.rules, policy{pattern: p, limiter: factory(rule.Pattern, quota)})
+	}
+
+	if policies.Default != nil {
+		quota := policies.Default.normalize()
+		if err := quota.validate(); err != nil {
+			return nil, fmt.Errorf("ratelimiter: default quota: %w", err)
+		}
+		table.fallback = factory(defaultPolicyName, quota)
+	}
+	return table, nil
+}
+
+// HTTPLimiter returns the limiter applying to an HTTP request.
+//
+// Parameters:
+// - method (string): The HTTP method of the request.
+// - path (string): The URL path of the request.
+//
+// Returns:
+// - Limiter: The limiter to apply, or nil if the request is not limited.
+func (t *PolicyTable) HTTPLimiter(method, path string) Limiter {
+	return t.resolve(method, path)
+}
+
+// GRPCLimiter returns the limiter applying to a gRPC call.
+//
+// Parameters:
+// - fullMethod (string): The full method of the call, e.g. "/orders.OrderService/Create".
+//
+// Returns:
+// - Limiter: The limiter to apply, or nil if the call is not limited.
+func (t *PolicyTable) GRPCLimiter(fullMethod string) Limiter {
+	return t.resolve("", fullMethod)
+}
+
+// resolve returns the limiter applying to the path or full method; method is empty for gRPC.
+func (t *PolicyTable) resolve(method, path string) Limiter {
+	for _, p := range t.exempt {
+		if p.match(method, path) {
+			return nil
+		}
+	}
+	for _, rule := range t.rules {
+		if rule.match(method, path) {
+			return rule.limiter
+		}
+	}
+	return t.fallback
+}
+
+// parsePattern parses a pattern with an optional HTTP method prefix.
+// The glob must start with '/' or '*'.
+func parsePattern(raw string) (pattern, error) {
+	p := pattern{glob: strings.TrimSpace(raw)}
+	if method, glob, ok := strings.Cut(p.glob, " "); ok {
+		p.method, p.glob = strings.ToUpper(method), strings.TrimSpace(glob)
+	}
+	// Paths and full methods start with a slash.
+	if !strings.HasPrefix(p.glob, "/") && !strings.HasPrefix(p.glob, "*") {
+		return pattern{}, fmt.Errorf("ratelimiter: invalid pattern %q", raw)
+	}
+	return p, nil
+}
+
+// match reports whether the pattern matches the path or full method; method is empty for gRPC.
+func (p pattern) match(method, path string) bool {
+	if p.method != "" && p.method != method {
+		return false
+	}
+	return glob.Match(p.glob, path)
+}
+
+// normalize maps an infinite rate, e.g. YAML's .inf, to rate.Inf.
+func (q Quota) normalize() Quota {
+	if math.IsInf(float64(q.Rate), 1) {
+		q.Rate = rate.Inf
+	}
+	return q
+}
+
+// validate checks that the quota allows requests at a positive rate with a positive burst,
+// or blocks them with a zero rate.
+func (q Quota) validate() error {
+	switch {
+	case q.Rate < 0 || q.Burst < 0:
+		return errors.New("rate and burst must not be negative")
+	case q.Rate > 0 && q.Rate != rate.Inf && q.Burst == 0:
+		return errors.New("burst must be positive")
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const policiesYAML = `
+default: {rate: 100, burst: 100}
+rules:
+  - pattern: "POST /api/orders*"
+    rate: 1
+    burst: 1
+  - pattern: "/orders.OrderService/Create*"
+    rate: 1
+    burst: 2
+  - pattern: "/api/reports/*"
+    rate: .inf
+    burst: 0
+exempt:
+  - "/health*"
+  - "/grpc.health.v1.Health/*"
+`
+
+// namedLimiter is a Limiter recording the quota name it was created for.
+type namedLimiter struct {
+	name  string
+	quota ratelimiter.Quota
+}
+
+func (n *namedLimiter) Allow(context.Context, string) (ratelimiter.Result, error) {
+	return ratelimiter.Result{Allowed: true}, nil
+}
+
+func namedFactory(name string, quota ratelimiter.Quota) ratelimiter.Limiter {
+	return &namedLimiter{name: name, quota: quota}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	policies, err := ratelimiter.LoadPolicies([]byte(policiesYAML))
+	require.NoError(t, err)
+
+	assert.Equal(t, &ratelimiter.Quota{Rate: 100, Burst: 100}, policies.Default)
+	require.Len(t, policies.Rules, 3)
+	assert.Equal(t, ratelimiter.Rule{Pattern: "POST /api/orders*", Quota: ratelimiter.Quota{Rate: 1, Burst: 1}}, policies.Rules[0])
+	assert.Equal(t, rate.Inf, policies.Rules[2].Rate)
+	assert.Equal(t, []string{"/health*", "/grpc.health.v1.Health/*"}, policies.Exempt)
+
+	policies, err = ratelimiter.LoadPolicies(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ratelimiter.Policies{}, policies)
+
+	_, err = ratelimiter.LoadPolicies([]byte("rules:\n  - pattern: /x\n    rps: 1\n"))
+	assert.Error(t, err, "unknown fields are rejected")
+}
+
+func TestPolicyTable_Resolve(t *testing.T) {
+	policies, err := ratelimiter.LoadPolicies([]byte(policiesYAML))
+	require.NoError(t, err)
+	table, err := ratelimiter.NewPolicyTable(policies, namedFactory)
+	require.NoError(t, err)
+
+	httpCases := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{method: http.MethodPost, path: "/api/orders", expected: "POST /api/orders*"},
+		{method: http.MethodPost, path: "/api/orders/42/items", expected: "POST /api/orders*"},
+		{method: http.MethodGet, path: "/api/orders", expected: "default"},
+		{method: http.MethodGet, path: "/api/reports/monthly", expected: "/api/reports/*"},
+		{method: http.MethodGet, path: "/health", expected: ""},
+		{method: http.MethodGet, path: "/healthz", expected: ""},
+	}
+	for _, tc := range httpCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			assertLimiterName(t, tc.expected, table.HTTPLimiter(tc.method, tc.path))
+		})
+	}
+
+	grpcCases := []struct {
+		fullMethod string
+		expected   string
+	}{
+		{fullMethod: "/orders.OrderService/CreateOrder", expected: "/orders.OrderService/Create*"},
+		{fullMethod: "/orders.OrderService/GetOrder", expected: "default"},
+		{fullMethod: "/grpc.health.v1.Health/Check", expected: ""},
+		// Patterns restricted to an HTTP method never match gRPC calls.
+		{fullMethod: "/api/orders", expected: "default"},
+	}
+	for _, tc := range grpcCases {
+		t.Run(tc.fullMethod, func(t *testing.T) {
+			assertLimiterName(t, tc.expected, table.GRPCLimiter(tc.fullMethod))
+		})
+	}
+
+	// Without a default, requests matching no rule are not limited.
+	policies.Default = nil
+	table, err = ratelimiter.NewPolicyTable(policies, namedFactory)
+	require.NoError(t, err)
+	assert.Nil(t, table.HTTPLimiter(http.MethodGet, "/api/orders"))
+	assert.Nil(t, table.GRPCLimiter("/orders.OrderService/GetOrder"))
+}
+
+func assertLimiterName(t *testing.T, expected string, limiter ratelimiter.Limiter) {
+	t.Helper()
+	if expected == "" {
+		assert.Nil(t, limiter)
+		return
+	}
+	require.IsType(t, &namedLimiter{}, limiter)
+	assert.Equal(t, expected, limiter.(*namedLimiter).name)
+}
+
+func TestNewPolicyTable_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policies ratelimiter.Policies
+	}{
+		{name: "Empty pattern", policies: ratelimiter.Policies{Rules: []ratelimiter.Rule{{Pattern: " ", Quota: ratelimiter.Quota{Rate: 1, Burst: 1}}}}},
+		{name: "Method without path", policies: ratelimiter.Policies{Exempt: []string{"GET "}}},
+		{name: "Duplicate pattern", policies: ratelimiter.Policies{Rules: []ratelimiter.Rule{
+			{Pattern: "/a", Quota: ratelimiter.Quota{Rate: 1, Burst: 1}},
+			{Pattern: "/a", Quota: ratelimiter.Quota{Rate: 2, Burst: 2}},
+		}}},
+		{name: "Zero burst", policies: ratelimiter.Policies{Rules: []ratelimiter.Rule{{Pattern: "/a", Quota: ratelimiter.Quota{Rate: 1}}}}},
+		{name: "Negative default", policies: ratelimiter.Policies{Default: &ratelimiter.Quota{Rate: -1, Burst: 1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ratelimiter.NewPolicyTable(tc.policies, namedFactory)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPolicyHTTP(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	table, err := ratelimiter.NewPolicyTable(ratelimiter.Policies{
+		Default: &ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 2},
+		Rules:   []ratelimiter.Rule{{Pattern: "POST /orders", Quota: ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 1}}},
+		Exempt:  []string{"/health"},
+	}, ratelimiter.LocalFactory())
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(ratelimiter.PolicyHTTP(table, env.mockLogger))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "OK") }
+	router.POST("/orders", ok)
+	router.GET("/orders", ok)
+	router.GET("/health", ok)
+
+	calls := []struct {
+		method       string
+		path         string
+		expectStatus int
+	}{
+		{method: http.MethodPost, path: "/orders", expectStatus: http.StatusOK},
+		{method: http.MethodPost, path: "/orders", expectStatus: http.StatusTooManyRequests},
+		{method: http.MethodGet, path: "/orders", expectStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/orders", expectStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/orders", expectStatus: http.StatusTooManyRequests},
+		{method: http.MethodGet, path: "/health", expectStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/health", expectStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/health", expectStatus: http.StatusOK},
+	}
+	for _, call := range calls {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(call.method, call.path, nil))
+		assert.Equal(t, call.expectStatus, w.Code, "%s %s", call.method, call.path)
+	}
+}
+
+func TestPolicyInterceptors(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	table, err := ratelimiter.NewPolicyTable(ratelimiter.Policies{
+		Rules:  []ratelimiter.Rule{{Pattern: "/orders.OrderService/*", Quota: ratelimiter.Quota{Rate: rate.Every(time.Hour), Burst: 1}}},
+		Exempt: []string{"/grpc.health.v1.Health/*"},
+	}, ratelimiter.RedisFactory(client, zap.NewNop()))
+	require.NoError(t, err)
+
+	unary := ratelimiter.PolicyUnaryInterceptor(table, env.mockLogger)
+	stream := ratelimiter.PolicyStreamInterceptor(table, env.mockLogger)
+	unaryHandler := func(ctx context.Context, req interface{}) (interface{}, error) { return "success", nil }
+	streamHandler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+
+	calls := []struct {
+		fullMethod string
+		expectCode codes.Code
+	}{
+		{fullMethod: "/orders.OrderService/Create", expectCode: codes.OK},
+		{fullMethod: "/orders.OrderService/Get", expectCode: codes.ResourceExhausted},
+		{fullMethod: "/grpc.health.v1.Health/Check", expectCode: codes.OK},
+		{fullMethod: "/grpc.health.v1.Health/Check", expectCode: codes.OK},
+		{fullMethod: "/users.UserService/Get", expectCode: codes.OK},
+	}
+	for _, call := range calls {
+		_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: call.fullMethod}, unaryHandler)
+		assert.Equal(t, call.expectCode, status.Code(err), call.fullMethod)
+	}
+	assert.True(t, server.Exists("ratelimit:/orders.OrderService/*:global"))
+
+	err = stream(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/orders.OrderService/Watch"}, streamHandler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	err = stream(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, streamHandler)
+	assert.NoError(t, err)
+}
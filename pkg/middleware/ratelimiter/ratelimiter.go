@@ -2,11 +2,50 @@
 Package ratelimiter provides HTTP and gRPC middleware interceptors for handling rate limiting.
 The package includes both unary and stream interceptors that enforce rate limits on incoming requests.
 When the rate limit is exceeded, the interceptor responds with a rate-limit error.
+
+Limits are enforced by a Limiter. Local keeps them in process with a token bucket from
+golang.org/x/time/rate, so each instance enforces its own limit; Redis shares them across
+every instance through Redis. The RateLimiter* functions take a *rate.Limiter and use a
+Local limiter; the Limiter* functions accept any Limiter.
+
+By default every request shares one limit. With WithHTTPKey or WithGRPCKey, requests are
+limited per client key, such as the client IP, an API key header, the authenticated subject,
+a gRPC metadata value or the peer address. Keys are passed to the Limiter: Redis stores one
+limit per key, and the RateLimiter* functions switch to a Registry creating one token bucket
+per key with the rate and burst of the given *rate.Limiter.
+
+Responses to limited requests carry the state of the limit: RateLimit-Limit,
+RateLimit-Remaining and RateLimit-Reset headers on HTTP responses, plus Retry-After on
+rejections, and the same values as lower-case gRPC trailers. ResourceExhausted errors also
+carry an errdetails.RetryInfo detail with the delay after which the call may succeed.
+
+With WithWait, requests over the limit wait for their turn, up to a maximum delay bounded
+by the request deadline, instead of being rejected right away; this suits internal batch
+callers preferring latency over errors.
+
+The Policy* functions apply different limits to different routes and gRPC methods, as
+configured by a PolicyTable built from Policies, which can be loaded from YAML.
+
+A Controller changes the rate and burst of its limiters at runtime, e.g. to absorb an
+incident without redeploying; it can expose them through an admin HTTP endpoint.
+
+Stream interceptors limit the opening of streams and, with WithMessageLimits, each message
+of the streams, so that a long-lived stream cannot push messages at an unlimited rate.
+
+The Limiter*ClientInterceptor functions apply a Limiter to outgoing gRPC calls, so that a
+client keeps its calls to a downstream service under a rate.
+
+When a Limiter backend fails, the request proceeds if the backend fails open; otherwise it
+is rejected with 503 Service Unavailable or codes.Unavailable, distinguishing an outage of
+the limiter from a client exceeding its limit, and a warning carrying the backend error is
+logged.
 */
 package ratelimiter
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/kmmania/er_commonlib/pkg/logger"
 
@@ -18,6 +57,104 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// options holds the optional settings of the middleware and interceptors.
+type options struct {
+	// httpKey extracts the client key of HTTP requests; nil for a global limit.
+	httpKey HTTPKeyFunc
+	// grpcKey extracts the client key of gRPC calls; nil for a global limit.
+	grpcKey GRPCKeyFunc
+	// idleTimeout is the idle timeout of the Registry used by the RateLimiter* functions.
+	idleTimeout time.Duration
+	// maxKeys is the maximum number of keys of the Registry used by the RateLimiter* functions.
+	maxKeys int
+	// maxDelay is the longest a request may wait for its turn; zero to reject right away.
+	maxDelay time.Duration
+	// maxWaiters is the maximum number of requests waiting at once; zero for no cap.
+	maxWaiters int
+	// waiters holds a token per waiting request when their number is capped.
+	waiters chan struct{}
+	// messages limits the messages of streams; nil to limit only their opening.
+	messages *MessageLimits
+}
+
+// Option configures optional behaviour of the middleware and interceptors.
+type Option func(*options)
+
+// WithHTTPKey limits HTTP requests per client key instead of globally.
+//
+// Parameters:
+// - keyFunc (HTTPKeyFunc): The key extractor, e.g. ByClientIP or ByHeader.
+//
+// Returns:
+// - Option: An option for the HTTP middleware; gRPC interceptors ignore it.
+func WithHTTPKey(keyFunc HTTPKeyFunc) Option {
+	return func(o *options) {
+		o.httpKey = keyFunc
+	}
+}
+
+// WithGRPCKey limits gRPC calls per client key instead of globally.
+//
+// Parameters:
+// - keyFunc (GRPCKeyFunc): The key extractor, e.g. ByMetadata or ByPeer.
+//
+// Returns:
+// - Option: An option for the gRPC interceptors; the HTTP middleware ignores it.
+func WithGRPCKey(keyFunc GRPCKeyFunc) Option {
+	return func(o *options) {
+		o.grpcKey = keyFunc
+	}
+}
+
+// WithIdleTimeout sets the time after which the per-key buckets created by the RateLimiter*
+// functions are evicted when unused, DefaultIdleTimeout by default.
+//
+// Parameters:
+// - idleTimeout (time.Duration): The idle timeout.
+//
+// Returns:
+// - Option: An option for the RateLimiter* functions; the Limiter* functions ignore it.
+func WithIdleTimeout(idleTimeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = idleTimeout
+	}
+}
+
+// WithMaxKeys sets the maximum number of per-key buckets held by the RateLimiter* functions,
+// DefaultMaxKeys by default; beyond it, the least recently used bucket is evicted.
+//
+// Parameters:
+// - maxKeys (int): The maximum number of buckets.
+//
+// Returns:
+// - Option: An option for the RateLimiter* functions; the Limiter* functions ignore it.
+func WithMaxKeys(maxKeys int) Option {
+	return func(o *options) {
+		o.maxKeys = maxKeys
+	}
+}
+
+// newOptions applies the options.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.maxDelay > 0 && o.maxWaiters > 0 {
+		o.waiters = make(chan struct{}, o.maxWaiters)
+	}
+	return o
+}
+
+// fromRate returns the Limiter enforcing rl: rl itself for a global limit, or a Registry
+// with the rate and burst of rl when requests are limited per key.
+func fromRate(rl *rate.Limiter, keyed bool, o *options) Limiter {
+	if !keyed {
+		return FromRate(rl)
+	}
+	return NewRegistry(rl.Limit(), rl.Burst(), o.idleTimeout, WithRegistryMaxKeys(o.maxKeys))
+}
+
 // RateLimiterHTTP returns a rate limiting middleware for HTTP requests.
 //
 // This middleware uses the provided `rate.Limiter` to control the rate of incoming
@@ -29,23 +166,14 @@ import (
 // Parameters:
 // - rl (*rate.Limiter): The `rate.Limiter` instance to use for rate limiting.
 // - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key; with a key, `rl` only provides the per-key rate and burst.
 //
 // Returns:
 // - gin.HandlerFunc: A `gin.HandlerFunc` that can be used as middleware in a Gin router.
-func RateLimiterHTTP(rl *rate.Limiter, logger logger.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if !rl.Allow() {
-			// Log the rate limit exceed
-			logger.Warn("HTTP rate limit exceeded",
-				zap.String("method", c.Request.Method),
-				zap.String("path", c.Request.URL.Path))
-			// Respond with a 429 status code
-			c.AbortWithStatusJSON(429, gin.H{"error": "Too many requests"})
-			return
-		}
-		// Continue processing the request
-		c.Next()
-	}
+func RateLimiterHTTP(rl *rate.Limiter, logger logger.Logger, opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
+	limiter := fromRate(rl, o.httpKey != nil, o)
+	return httpMiddleware(func(*gin.Context) Limiter { return limiter }, logger, o)
 }
 
 // RateLimiterUnaryInterceptor returns a gRPC UnaryServerInterceptor that limits
@@ -60,27 +188,15 @@ func RateLimiterHTTP(rl *rate.Limiter, logger logger.Logger) gin.HandlerFunc {
 // Parameters:
 // - rl (*rate.Limiter): The `rate.Limiter` instance to use for rate limiting.
 // - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key; with a key, `rl` only provides the per-key rate and burst.
 //
 // Returns:
 //   - grpc.UnaryServerInterceptor: A `grpc.UnaryServerInterceptor` that can be used with `grpc.Server`'s
 //     `UnaryInterceptor` option.
-func RateLimiterUnaryInterceptor(rl *rate.Limiter, logger logger.Logger) grpc.UnaryServerInterceptor {
-	return func(
-		ctx context.Context,
-		req interface{},
-		info *grpc.UnaryServerInfo,
-		handler grpc.UnaryHandler,
-	) (interface{}, error) {
-		if !rl.Allow() {
-			// Log the rate limit exceed
-			logger.Warn("gRPC unary rate limit exceeded", zap.String("method", info.FullMethod))
-			// Return a ResourceExhausted error
-			return nil, status.Error(codes.ResourceExhausted, "too many requests")
-		}
-
-		// Proceed with the handler
-		return handler(ctx, req)
-	}
+func RateLimiterUnaryInterceptor(rl *rate.Limiter, logger logger.Logger, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	limiter := fromRate(rl, o.grpcKey != nil, o)
+	return unaryInterceptor(func(string) Limiter { return limiter }, logger, o)
 }
 
 // RateLimiterStreamInterceptor returns a gRPC StreamServerInterceptor that limits
@@ -95,25 +211,247 @@ func RateLimiterUnaryInterceptor(rl *rate.Limiter, logger logger.Logger) grpc.Un
 // Parameters:
 // - rl (*rate.Limiter): The `rate.Limiter` instance to use for rate limiting.
 // - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key; with a key, `rl` only provides the per-key rate and burst.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A `grpc.StreamServerInterceptor` that can be used with `grpc.Server`'s
+//     `StreamInterceptor` option.
+func RateLimiterStreamInterceptor(rl *rate.Limiter, logger logger.Logger, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	limiter := fromRate(rl, o.grpcKey != nil, o)
+	return streamInterceptor(func(string) Limiter { return limiter }, logger, o)
+}
+
+// LimiterHTTP returns a rate limiting middleware for HTTP requests using any Limiter.
+//
+// It behaves like RateLimiterHTTP. If the limiter fails closed, the middleware logs a
+// warning with the limiter error and responds with a 503 Service Unavailable status code
+// instead of 429.
+//
+// Parameters:
+// - limiter (Limiter): The limiter deciding whether requests may proceed.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key.
+//
+// Returns:
+// - gin.HandlerFunc: A `gin.HandlerFunc` that can be used as middleware in a Gin router.
+func LimiterHTTP(limiter Limiter, logger logger.Logger, opts ...Option) gin.HandlerFunc {
+	return httpMiddleware(func(*gin.Context) Limiter { return limiter }, logger, newOptions(opts))
+}
+
+// LimiterUnaryInterceptor returns a gRPC UnaryServerInterceptor that limits the rate of
+// incoming unary gRPC requests using any Limiter.
+//
+// It behaves like RateLimiterUnaryInterceptor. If the limiter fails closed, the interceptor
+// logs a warning with the limiter error and returns a `codes.Unavailable` error instead of
+// `codes.ResourceExhausted`.
+//
+// Parameters:
+// - limiter (Limiter): The limiter deciding whether requests may proceed.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A `grpc.UnaryServerInterceptor` that can be used with `grpc.Server`'s
+//     `UnaryInterceptor` option.
+func LimiterUnaryInterceptor(limiter Limiter, logger logger.Logger, opts ...Option) grpc.UnaryServerInterceptor {
+	return unaryInterceptor(func(string) Limiter { return limiter }, logger, newOptions(opts))
+}
+
+// LimiterStreamInterceptor returns a gRPC StreamServerInterceptor that limits the rate of
+// incoming streaming gRPC requests using any Limiter.
+//
+// It behaves like RateLimiterStreamInterceptor. If the limiter fails closed, the interceptor
+// logs a warning with the limiter error and returns a `codes.Unavailable` error instead of
+// `codes.ResourceExhausted`.
+//
+// Parameters:
+// - limiter (Limiter): The limiter deciding whether streams may proceed.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key.
 //
 // Returns:
 //   - grpc.StreamServerInterceptor: A `grpc.StreamServerInterceptor` that can be used with `grpc.Server`'s
 //     `StreamInterceptor` option.
-func RateLimiterStreamInterceptor(rl *rate.Limiter, logger logger.Logger) grpc.StreamServerInterceptor {
+func LimiterStreamInterceptor(limiter Limiter, logger logger.Logger, opts ...Option) grpc.StreamServerInterceptor {
+	return streamInterceptor(func(string) Limiter { return limiter }, logger, newOptions(opts))
+}
+
+// PolicyHTTP returns a rate limiting middleware for HTTP requests applying the limit of the
+// policy matching each request's method and path. Exempt requests, and requests matching
+// no policy when the table has no default, are not limited.
+//
+// It otherwise behaves like LimiterHTTP.
+//
+// Parameters:
+// - table (*PolicyTable): The policy table.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key.
+//
+// Returns:
+// - gin.HandlerFunc: A `gin.HandlerFunc` that can be used as middleware in a Gin router.
+func PolicyHTTP(table *PolicyTable, logger logger.Logger, opts ...Option) gin.HandlerFunc {
+	return httpMiddleware(func(c *gin.Context) Limiter {
+		return table.HTTPLimiter(c.Request.Method, c.Request.URL.Path)
+	}, logger, newOptions(opts))
+}
+
+// PolicyUnaryInterceptor returns a gRPC UnaryServerInterceptor applying the limit of the
+// policy matching each call's full method. Exempt calls, and calls matching no policy
+// when the table has no default, are not limited.
+//
+// It otherwise behaves like LimiterUnaryInterceptor.
+//
+// Parameters:
+// - table (*PolicyTable): The policy table.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key.
+//
+// Returns:
+//   - grpc.UnaryServerInterceptor: A `grpc.UnaryServerInterceptor` that can be used with `grpc.Server`'s
+//     `UnaryInterceptor` option.
+func PolicyUnaryInterceptor(table *PolicyTable, logger logger.Logger, opts ...Option) grpc.UnaryServerInterceptor {
+	return unaryInterceptor(table.GRPCLimiter, logger, newOptions(opts))
+}
+
+// PolicyStreamInterceptor returns a gRPC StreamServerInterceptor applying the limit of the
+// policy matching each stream's full method. Exempt streams, and streams matching no policy
+// when the table has no default, are not limited.
+//
+// It otherwise behaves like LimiterStreamInterceptor.
+//
+// Parameters:
+// - table (*PolicyTable): The policy table.
+// - logger (logger.Logger): The logger instance used to log rate limiting events.
+// - opts (...Option): Optional settings such as the client key.
+//
+// Returns:
+//   - grpc.StreamServerInterceptor: A `grpc.StreamServerInterceptor` that can be used with `grpc.Server`'s
+//     `StreamInterceptor` option.
+func PolicyStreamInterceptor(table *PolicyTable, logger logger.Logger, opts ...Option) grpc.StreamServerInterceptor {
+	return streamInterceptor(table.GRPCLimiter, logger, newOptions(opts))
+}
+
+// httpMiddleware returns the HTTP middleware applying the limiter returned by resolve
+// for each request; requests for which it returns nil are not limited.
+func httpMiddleware(resolve func(c *gin.Context) Limiter, logger logger.Logger, o *options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := resolve(c)
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		result, err := o.allow(c.Request.Context(), limiter, o.httpKeyOf(c))
+		if err == nil {
+			setHeaders(c, result)
+		}
+		if !result.Allowed {
+			if err != nil {
+				// The limiter failed closed
+				logger.Warn("HTTP rate limiter unavailable",
+					zap.Error(err),
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Rate limiter unavailable"})
+				return
+			}
+			// Log the rate limit exceed
+			logger.Warn("HTTP rate limit exceeded",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path))
+			// Respond with a 429 status code
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		// Continue processing the request
+		c.Next()
+	}
+}
+
+// unaryInterceptor returns the unary interceptor applying the limiter returned by resolve
+// for each call; calls for which it returns nil are not limited.
+func unaryInterceptor(resolve func(fullMethod string) Limiter, logger logger.Logger, o *options) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		limiter := resolve(info.FullMethod)
+		if limiter == nil {
+			return handler(ctx, req)
+		}
+
+		result, err := o.allow(ctx, limiter, o.grpcKeyOf(ctx, info.FullMethod))
+		if err == nil {
+			setTrailer(ctx, result)
+		}
+		if !result.Allowed {
+			if err != nil {
+				// The limiter failed closed
+				logger.Warn("gRPC unary rate limiter unavailable", zap.Error(err), zap.String("method", info.FullMethod))
+				return nil, status.Error(codes.Unavailable, "rate limiter unavailable")
+			}
+			// Log the rate limit exceed
+			logger.Warn("gRPC unary rate limit exceeded", zap.String("method", info.FullMethod))
+			// Return a ResourceExhausted error
+			return nil, exhausted("too many requests", result)
+		}
+
+		// Proceed with the handler
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor returns the stream interceptor applying the limiter returned by resolve
+// for each stream; streams for which it returns nil are not limited.
+func streamInterceptor(resolve func(fullMethod string) Limiter, logger logger.Logger, o *options) grpc.StreamServerInterceptor {
 	return func(
 		srv interface{},
 		ss grpc.ServerStream,
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		if !rl.Allow() {
+		limiter := resolve(info.FullMethod)
+		if limiter == nil {
+			return handler(srv, ss)
+		}
+
+		ctx := ss.Context()
+		result, err := o.allow(ctx, limiter, o.grpcKeyOf(ctx, info.FullMethod))
+		if err == nil {
+			ss.SetTrailer(trailer(result))
+		}
+		if !result.Allowed {
+			if err != nil {
+				// The limiter failed closed
+				logger.Warn("gRPC stream rate limiter unavailable", zap.Error(err), zap.String("method", info.FullMethod))
+				return status.Error(codes.Unavailable, "rate limiter unavailable")
+			}
 			// Log the rate limit exceed
 			logger.Warn("gRPC stream rate limit exceeded", zap.String("method", info.FullMethod))
 			// Return a ResourceExhausted error
-			return status.Errorf(codes.ResourceExhausted, "too many requests: rate limiting on stream")
+			return exhausted("too many requests: rate limiting on stream", result)
 		}
 
-		// Proceed with the handler
-		return handler(srv, ss)
+		// Proceed with the handler, limiting the messages of the stream
+		return handler(srv, o.limitMessages(ss, logger, info.FullMethod))
+	}
+}
+
+// httpKeyOf returns the client key of an HTTP request, or "" for a global limit.
+func (o *options) httpKeyOf(c *gin.Context) string {
+	if o.httpKey == nil {
+		return ""
+	}
+	return o.httpKey(c)
+}
+
+// grpcKeyOf returns the client key of a gRPC call, or "" for a global limit.
+func (o *options) grpcKeyOf(ctx context.Context, fullMethod string) string {
+	if o.grpcKey == nil {
+		return ""
 	}
+	return o.grpcKey(ctx, fullMethod)
 }
@@ -224,6 +224,60 @@ func TestRateLimiterStreamInterceptor(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_PerKey(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	t.Run("HTTP", func(t *testing.T) {
+		rl := rate.NewLimiter(1, 1)
+		router := gin.New()
+		router.Use(ratelimiter.RateLimiterHTTP(rl, env.mockLogger, ratelimiter.WithHTTPKey(ratelimiter.ByHeader("X-API-Key"))))
+		router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+		for _, call := range []struct {
+			key          string
+			expectStatus int
+		}{
+			{key: "a", expectStatus: http.StatusOK},
+			{key: "a", expectStatus: http.StatusTooManyRequests},
+			{key: "b", expectStatus: http.StatusOK},
+			{key: "", expectStatus: http.StatusOK},
+			{key: "", expectStatus: http.StatusTooManyRequests},
+		} {
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-API-Key", call.key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, call.expectStatus, w.Code, "key %q", call.key)
+		}
+	})
+
+	t.Run("gRPC", func(t *testing.T) {
+		keyOpt := ratelimiter.WithGRPCKey(ratelimiter.ByMetadata("x-api-key"))
+		unary := ratelimiter.RateLimiterUnaryInterceptor(rate.NewLimiter(1, 1), env.mockLogger, keyOpt)
+		stream := ratelimiter.RateLimiterStreamInterceptor(rate.NewLimiter(1, 1), env.mockLogger, keyOpt)
+		unaryHandler := func(ctx context.Context, req interface{}) (interface{}, error) { return "success", nil }
+		streamHandler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+
+		for _, call := range []struct {
+			key        string
+			expectCode codes.Code
+		}{
+			{key: "a", expectCode: codes.OK},
+			{key: "a", expectCode: codes.ResourceExhausted},
+			{key: "b", expectCode: codes.OK},
+		} {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", call.key))
+
+			_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc.Service/Method"}, unaryHandler)
+			assert.Equal(t, call.expectCode, status.Code(err), "unary key %q", call.key)
+
+			err = stream(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/svc.Service/Stream"}, streamHandler)
+			assert.Equal(t, call.expectCode, status.Code(err), "stream key %q", call.key)
+		}
+	})
+}
+
 type mockServerStream struct {
 	ctx context.Context
 }
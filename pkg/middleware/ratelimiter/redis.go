@@ -0,0 +1,184 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultKeyPrefix is the default prefix of the Redis keys holding rate limits.
+	DefaultKeyPrefix = "ratelimit:"
+
+	// globalKey names the limit used when the key is empty.
+	globalKey = "global"
+)
+
+// gcraScript implements the generic cell rate algorithm. The key holds the theoretical
+// arrival time (TAT) of the next request, in microseconds of the Redis clock: a request is
+// allowed if it does not arrive more than burst emission intervals before the TAT, and
+// moves the TAT forward by one interval. Using the Redis clock keeps instances with skewed
+// clocks consistent.
+//
+// ARGV[1] is the burst, ARGV[2] the emission interval and ARGV[3] the longest a request may
+// wait, in microseconds; a request arriving up to that long before it is allowed is admitted
+// after a delay. The script returns whether the request is allowed, the remaining requests,
+// and the retry, reset and wait delays in microseconds.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local burst = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local max_delay = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = redis.call('GET', KEYS[1])
+if tat then
+	tat = math.max(tonumber(tat), now)
+else
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - burst * interval
+if now < allow_at - max_delay then
+	return {0, 0, allow_at - now, tat - now, 0}
+end
+
+local reset_after = new_tat - now
+redis.call('SET', KEYS[1], string.format('%.0f', new_tat), 'PX', math.ceil(reset_after / 1000))
+return {1, math.max(math.floor((now - allow_at) / interval), 0), 0, reset_after, math.max(allow_at - now, 0)}
+`)
+
+// Redis is a Limiter sharing its limits across instances through Redis, using the generic
+// cell rate algorithm (GCRA). It behaves like a token bucket refilled at limit requests per
+// second, holding up to burst requests, and stores a single timestamp per key.
+type Redis struct {
+	// client is the Redis client holding the limits.
+	client redis.UniversalClient
+	// logger provides structured logging for this limiter's operations.
+	logger logger.Logger
+	// quota holds the rate and burst, replaced as a whole by SetQuota.
+	quota atomic.Pointer[Quota]
+	// prefix is prepended to every key.
+	prefix string
+	// failOpen allows requests when Redis cannot be reached.
+	failOpen bool
+}
+
+// RedisOption configures optional behaviour of a Redis limiter.
+type RedisOption func(*Redis)
+
+// WithRedisKeyPrefix sets the prefix of the Redis keys holding the limits,
+// DefaultKeyPrefix by default.
+//
+// Parameters:
+// - prefix (string): The key prefix.
+//
+// Returns:
+// - RedisOption: An option to pass to NewRedis.
+func WithRedisKeyPrefix(prefix string) RedisOption {
+	return func(r *Redis) {
+		r.prefix = prefix
+	}
+}
+
+// WithFailOpen sets whether requests are allowed when Redis cannot be reached. By default
+// the limiter fails closed and denies them.
+//
+// Parameters:
+// - failOpen (bool): True to allow requests when Redis cannot be reached.
+//
+// Returns:
+// - RedisOption: An option to pass to NewRedis.
+func WithFailOpen(failOpen bool) RedisOption {
+	return func(r *Redis) {
+		r.failOpen = failOpen
+	}
+}
+
+// NewRedis creates and returns a new Redis limiter.
+//
+// Parameters:
+// - client (redis.UniversalClient): The Redis client holding the limits.
+// - logger (logger.Logger): A logger instance for logging Redis errors.
+// - limit (rate.Limit): The number of requests allowed per second, per key.
+// - burst (int): The maximum number of requests allowed at once, per key.
+// - opts (...RedisOption): Optional settings such as the key prefix and the failure policy.
+//
+// Returns:
+// - *Redis: An initialized Redis limiter.
+func NewRedis(client redis.UniversalClient, logger logger.Logger, limit rate.Limit, burst int, opts ...RedisOption) *Redis {
+	r := &Redis{
+		client: client,
+		logger: logger,
+		prefix: DefaultKeyPrefix,
+	}
+	r.quota.Store(&Quota{Rate: limit, Burst: burst})
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Allow consumes one request from the limit stored under the prefixed key. If Redis cannot
+// be reached, it logs the error and returns it with a Result allowing the request only if
+// the limiter fails open.
+func (r *Redis) Allow(ctx context.Context, key string) (Result, error) {
+	reservation, err := r.Reserve(ctx, key, 0)
+	return reservation.Result, err
+}
+
+// Reserve consumes one request from the limit stored under the prefixed key if it can
+// proceed within maxDelay. Reservations cannot be cancelled: a request giving up waiting
+// still counts against the limit. If Redis cannot be reached, it logs the error and returns
+// it with a Reservation allowing the request right away only if the limiter fails open.
+func (r *Redis) Reserve(ctx context.Context, key string, maxDelay time.Duration) (Reservation, error) {
+	quota := r.quota.Load()
+	switch {
+	case quota.Rate == rate.Inf:
+		return Reservation{Result: Result{Allowed: true, Limit: quota.Burst, Remaining: quota.Burst}}, nil
+	case quota.Rate <= 0 || quota.Burst <= 0:
+		return Reservation{Result: Result{Limit: quota.Burst}}, nil
+	}
+
+	if key == "" {
+		key = globalKey
+	}
+	interval := float64(time.Second/time.Microsecond) / float64(quota.Rate)
+	values, err := gcraScript.Run(ctx, r.client, []string{r.prefix + key},
+		quota.Burst, interval, maxDelay.Microseconds()).Int64Slice()
+	if err != nil {
+		r.logger.Error("Error checking rate limit in Redis",
+			zap.String("key", key), zap.Bool("failOpen", r.failOpen), zap.Error(err))
+		return Reservation{Result: Result{Allowed: r.failOpen, Limit: quota.Burst}}, err
+	}
+
+	return Reservation{
+		Result: Result{
+			Allowed:    values[0] == 1,
+			Limit:      quota.Burst,
+			Remaining:  int(values[1]),
+			RetryAfter: time.Duration(values[2]) * time.Microsecond,
+			ResetAfter: time.Duration(values[3]) * time.Microsecond,
+		},
+		Delay: time.Duration(values[4]) * time.Microsecond,
+	}, nil
+}
+
+// Quota returns the rate and burst of each key's limit.
+func (r *Redis) Quota() Quota {
+	return *r.quota.Load()
+}
+
+// SetQuota changes the rate and burst of every key's limit. The stored arrival times are
+// kept, so requests already counted stay counted under the new quota.
+func (r *Redis) SetQuota(quota Quota) {
+	r.quota.Store(&quota)
+}
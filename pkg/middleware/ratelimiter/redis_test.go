@@ -0,0 +1,116 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+func setUpRedisLimiter(t *testing.T, limit rate.Limit, burst int, opts ...ratelimiter.RedisOption) (*ratelimiter.Redis, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	server.SetTime(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return ratelimiter.NewRedis(client, zap.NewNop(), limit, burst, opts...), server
+}
+
+func TestRedis_Allow(t *testing.T) {
+	limiter, server := setUpRedisLimiter(t, 10, 3)
+	ctx := context.Background()
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		result, err := limiter.Allow(ctx, "client")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, remaining, result.Remaining)
+		assert.Equal(t, time.Duration(3-remaining)*100*time.Millisecond, result.ResetAfter)
+	}
+	assert.True(t, server.Exists("ratelimit:client"))
+
+	result, err := limiter.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 100*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, 300*time.Millisecond, result.ResetAfter)
+
+	// Keys are limited independently.
+	result, err = limiter.Allow(ctx, "other")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// One request is replenished every emission interval.
+	server.SetTime(time.Date(2025, 1, 1, 0, 0, 0, int(150*time.Millisecond), time.UTC))
+	result, err = limiter.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	result, err = limiter.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 50*time.Millisecond, result.RetryAfter)
+}
+
+func TestRedis_Allow_SharedAcrossInstances(t *testing.T) {
+	first, server := setUpRedisLimiter(t, 1, 2, ratelimiter.WithRedisKeyPrefix("api:"))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	second := ratelimiter.NewRedis(client, zap.NewNop(), 1, 2, ratelimiter.WithRedisKeyPrefix("api:"))
+	ctx := context.Background()
+
+	result, err := first.Allow(ctx, "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = second.Allow(ctx, "")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = first.Allow(ctx, "")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	assert.True(t, server.Exists("api:global"))
+}
+
+func TestRedis_Allow_Unreachable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []ratelimiter.RedisOption
+		expected bool
+	}{
+		{name: "Fails closed by default", expected: false},
+		{name: "Fails open", opts: []ratelimiter.RedisOption{ratelimiter.WithFailOpen(true)}, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter, server := setUpRedisLimiter(t, 10, 3, tc.opts...)
+			server.Close()
+
+			result, err := limiter.Allow(context.Background(), "client")
+			assert.Error(t, err)
+			assert.Equal(t, tc.expected, result.Allowed)
+		})
+	}
+}
+
+func TestRedis_Allow_Unlimited(t *testing.T) {
+	limiter, server := setUpRedisLimiter(t, rate.Inf, 1)
+
+	for i := 0; i < 5; i++ {
+		result, err := limiter.Allow(context.Background(), "client")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	assert.False(t, server.Exists("ratelimit:client"))
+}
@@ -0,0 +1,199 @@
+package ratelimiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultIdleTimeout is the default time after which an unused per-key limiter is evicted.
+	DefaultIdleTimeout = 10 * time.Minute
+
+	// DefaultMaxKeys is the default maximum number of per-key limiters held at once.
+	DefaultMaxKeys = 100000
+)
+
+// Registry is an in-process Limiter holding one token bucket per key. Buckets are created
+// on a key's first request and evicted once they have been unused for the idle timeout and
+// are full again, so that eviction never grants a client more requests than it would
+// otherwise get.
+//
+// Keys often come from the client, e.g. a header or a forwarded IP, so a client rotating
+// them could create buckets faster than they go idle. The number of buckets is therefore
+// capped: beyond the maximum, the least recently used bucket is evicted, even if it is not
+// full, which may reset the limit of a client that has been quiet the longest.
+type Registry struct {
+	// quotaMu makes quota updates atomic: decisions hold it for reading, SetQuota for writing.
+	quotaMu sync.RWMutex
+	// limit is the number of requests allowed per second, per key; guarded by quotaMu.
+	limit rate.Limit
+	// burst is the maximum number of requests allowed at once, per key; guarded by quotaMu.
+	burst int
+	// idleTimeout is the time after which an unused bucket may be evicted.
+	idleTimeout time.Duration
+	// maxKeys is the maximum number of buckets held at once.
+	maxKeys int
+
+	// mu guards entries, recency and lastSweep.
+	mu sync.Mutex
+	// entries holds the element of each key in recency.
+	entries map[string]*list.Element
+	// recency orders the buckets from the most to the least recently used.
+	recency *list.List
+	// lastSweep is when idle buckets were last evicted.
+	lastSweep time.Time
+}
+
+// registryEntry is the bucket of a key.
+type registryEntry struct {
+	// key is the key of the bucket.
+	key string
+	// limiter is the token bucket.
+	limiter *rate.Limiter
+	// lastSeen is when the key was last used; guarded by Registry.mu.
+	lastSeen time.Time
+}
+
+// RegistryOption configures optional behaviour of a Registry.
+type RegistryOption func(*Registry)
+
+// WithRegistryMaxKeys sets the maximum number of buckets held at once, DefaultMaxKeys by default.
+//
+// Parameters:
+// - maxKeys (int): The maximum number of buckets; values below one are ignored.
+//
+// Returns:
+// - RegistryOption: An option to pass to NewRegistry.
+func WithRegistryMaxKeys(maxKeys int) RegistryOption {
+	return func(r *Registry) {
+		if maxKeys > 0 {
+			r.maxKeys = maxKeys
+		}
+	}
+}
+
+// NewRegistry creates and returns a new Registry.
+//
+// Parameters:
+// - limit (rate.Limit): The number of requests allowed per second, per key.
+// - burst (int): The maximum number of requests allowed at once, per key.
+// - idleTimeout (time.Duration): The time after which an unused bucket may be evicted; DefaultIdleTimeout if zero.
+// - opts (...RegistryOption): Optional settings such as the maximum number of buckets.
+//
+// Returns:
+// - *Registry: An initialized Registry.
+func NewRegistry(limit rate.Limit, burst int, idleTimeout time.Duration, opts ...RegistryOption) *Registry {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	r := &Registry{
+		limit:       limit,
+		burst:       burst,
+		idleTimeout: idleTimeout,
+		maxKeys:     DefaultMaxKeys,
+		entries:     make(map[string]*list.Element),
+		recency:     list.New(),
+		lastSweep:   time.Now(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Allow consumes one token from the key's bucket, creating it if needed. It never fails.
+func (r *Registry) Allow(_ context.Context, key string) (Result, error) {
+	r.quotaMu.RLock()
+	defer r.quotaMu.RUnlock()
+
+	now := time.Now()
+	limiter := r.limiter(key, now)
+	allowed := limiter.AllowN(now, 1)
+	return bucketResult(limiter, now, allowed), nil
+}
+
+// Reserve reserves one token from the key's bucket, creating it if needed, if it is
+// available within maxDelay. It never fails.
+func (r *Registry) Reserve(_ context.Context, key string, maxDelay time.Duration) (Reservation, error) {
+	r.quotaMu.RLock()
+	defer r.quotaMu.RUnlock()
+	return reserveBucket(r.limiter(key, time.Now()), maxDelay), nil
+}
+
+// Quota returns the rate and burst of each key's bucket.
+func (r *Registry) Quota() Quota {
+	r.quotaMu.RLock()
+	defer r.quotaMu.RUnlock()
+	return Quota{Rate: r.limit, Burst: r.burst}
+}
+
+// SetQuota changes the rate and burst of every bucket, keeping the tokens they hold.
+func (r *Registry) SetQuota(quota Quota) {
+	r.quotaMu.Lock()
+	defer r.quotaMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.limit, r.burst = quota.Rate, quota.Burst
+	now := time.Now()
+	for _, element := range r.entries {
+		setBucketQuota(element.Value.(*registryEntry).limiter, now, quota)
+	}
+}
+
+// Len returns the number of buckets currently held.
+//
+// Returns:
+// - int: The number of keys with a bucket.
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// limiter returns the bucket of the key, creating it if needed, evicts idle buckets once
+// per idle timeout, and evicts the least recently used bucket when the registry is full.
+func (r *Registry) limiter(key string, now time.Time) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) >= r.idleTimeout {
+		r.sweep(now)
+	}
+
+	element, ok := r.entries[key]
+	if ok {
+		r.recency.MoveToFront(element)
+	} else {
+		for len(r.entries) >= r.maxKeys {
+			r.remove(r.recency.Back())
+		}
+		element = r.recency.PushFront(&registryEntry{key: key, limiter: rate.NewLimiter(r.limit, r.burst)})
+		r.entries[key] = element
+	}
+	entry := element.Value.(*registryEntry)
+	entry.lastSeen = now
+	return entry.limiter
+}
+
+// sweep evicts the buckets that are idle and full. The caller must hold mu.
+func (r *Registry) sweep(now time.Time) {
+	for _, element := range r.entries {
+		entry := element.Value.(*registryEntry)
+		full := r.limit == rate.Inf || entry.limiter.TokensAt(now) >= float64(r.burst)
+		if full && now.Sub(entry.lastSeen) >= r.idleTimeout {
+			r.remove(element)
+		}
+	}
+	r.lastSweep = now
+}
+
+// remove evicts the bucket held by the element. The caller must hold mu.
+func (r *Registry) remove(element *list.Element) {
+	r.recency.Remove(element)
+	delete(r.entries, element.Value.(*registryEntry).key)
+}
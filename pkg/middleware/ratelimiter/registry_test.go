@@ -0,0 +1,94 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestRegistry_Allow(t *testing.T) {
+	registry := ratelimiter.NewRegistry(rate.Every(time.Hour), 1, 0)
+	ctx := context.Background()
+
+	testCases := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "alice", expected: true},
+		{key: "alice", expected: false},
+		{key: "bob", expected: true},
+		{key: "", expected: true},
+		{key: "", expected: false},
+		{key: "bob", expected: false},
+	}
+
+	for _, tc := range testCases {
+		result, err := registry.Allow(ctx, tc.key)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, result.Allowed, "key %q", tc.key)
+		assert.Equal(t, 1, result.Limit)
+	}
+	assert.Equal(t, 3, registry.Len())
+}
+
+func TestRegistry_IdleEviction(t *testing.T) {
+	ctx := context.Background()
+
+	// Buckets that are idle and full again are evicted.
+	registry := ratelimiter.NewRegistry(1000, 1, 20*time.Millisecond)
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := registry.Allow(ctx, key)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, registry.Len())
+
+	time.Sleep(30 * time.Millisecond)
+	_, err := registry.Allow(ctx, "d")
+	require.NoError(t, err)
+	assert.Equal(t, 1, registry.Len())
+
+	// Buckets still refilling are kept, so eviction never resets a limit.
+	registry = ratelimiter.NewRegistry(rate.Every(time.Hour), 1, 20*time.Millisecond)
+	_, err = registry.Allow(ctx, "a")
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = registry.Allow(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, registry.Len())
+
+	result, err := registry.Allow(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+func TestRegistry_MaxKeys(t *testing.T) {
+	registry := ratelimiter.NewRegistry(rate.Every(time.Hour), 1, 0, ratelimiter.WithRegistryMaxKeys(2))
+	ctx := context.Background()
+
+	// Once full, a new key evicts the least recently used one: "a" was used after "b".
+	testCases := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "a", expected: true},
+		{key: "b", expected: true},
+		{key: "a", expected: false},
+		{key: "c", expected: true},
+		{key: "a", expected: false},
+		{key: "b", expected: true},
+	}
+
+	for _, tc := range testCases {
+		result, err := registry.Allow(ctx, tc.key)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, result.Allowed, "key %q", tc.key)
+		assert.LessOrEqual(t, registry.Len(), 2)
+	}
+}
@@ -0,0 +1,84 @@
+package ratelimiter
+
+import (
+	"context"
+	"time"
+)
+
+// WithWait makes requests over the limit wait for their turn instead of being rejected right
+// away, as long as their turn comes within maxDelay and before the request context's deadline.
+// At most maxWaiters requests wait at once; requests beyond that are rejected, so that a
+// burst cannot pile up goroutines. Waiting needs a limiter implementing Reserver, as every
+// limiter of this package does; other limiters keep rejecting right away.
+//
+// Parameters:
+// - maxDelay (time.Duration): The longest a request may wait.
+// - maxWaiters (int): The maximum number of requests waiting at once; zero for no cap.
+//
+// Returns:
+// - Option: An option for the middleware and interceptors.
+func WithWait(maxDelay time.Duration, maxWaiters int) Option {
+	return func(o *options) {
+		o.maxDelay = maxDelay
+		o.maxWaiters = maxWaiters
+	}
+}
+
+// allow admits a request, waiting for its turn in wait mode.
+func (o *options) allow(ctx context.Context, limiter Limiter, key string) (Result, error) {
+	if _, ok := limiter.(Reserver); !ok || o.maxDelay <= 0 {
+		return limiter.Allow(ctx, key)
+	}
+	reservation, err := o.reserve(ctx, limiter, key)
+	return reservation.Result, err
+}
+
+// reserve admits a request like allow, waiting for its turn in wait mode, and returns its
+// reservation so that the caller can give the request back if it does not proceed after
+// all. Requests admitted by limiters that do not implement Reserver cannot be given back.
+func (o *options) reserve(ctx context.Context, limiter Limiter, key string) (Reservation, error) {
+	reserver, ok := limiter.(Reserver)
+	if !ok {
+		result, err := limiter.Allow(ctx, key)
+		return Reservation{Result: result}, err
+	}
+
+	maxDelay := max(o.maxDelay, 0)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxDelay = max(min(maxDelay, time.Until(deadline)), 0)
+	}
+
+	reservation, err := reserver.Reserve(ctx, key, maxDelay)
+	if err != nil || !reservation.Allowed || reservation.Delay <= 0 {
+		return reservation, err
+	}
+
+	if o.waiters != nil {
+		select {
+		case o.waiters <- struct{}{}:
+			defer func() { <-o.waiters }()
+		default:
+			// Too many requests are already waiting.
+			reservation.Cancel()
+			return Reservation{Result: rejected(reservation)}, nil
+		}
+	}
+
+	timer := time.NewTimer(reservation.Delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return reservation, nil
+	case <-ctx.Done():
+		reservation.Cancel()
+		return Reservation{Result: rejected(reservation)}, nil
+	}
+}
+
+// rejected returns the result of a reserved request that did not wait for its turn.
+func rejected(reservation Reservation) Result {
+	result := reservation.Result
+	result.Allowed = false
+	result.RetryAfter = reservation.Delay
+	return result
+}
@@ -0,0 +1,127 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/ratelimiter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimiterHTTP_Wait(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	testCases := []struct {
+		name         string
+		limit        rate.Limit
+		maxDelay     time.Duration
+		expectStatus int
+		minElapsed   time.Duration
+	}{
+		{name: "Waits for its turn", limit: rate.Every(50 * time.Millisecond), maxDelay: time.Second, expectStatus: http.StatusOK, minElapsed: 40 * time.Millisecond},
+		{name: "Rejected beyond the maximum delay", limit: rate.Every(time.Second), maxDelay: 50 * time.Millisecond, expectStatus: http.StatusTooManyRequests},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ratelimiter.LimiterHTTP(ratelimiter.NewLocal(tc.limit, 1), env.mockLogger, ratelimiter.WithWait(tc.maxDelay, 0)))
+			router.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "OK") })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+			require.Equal(t, http.StatusOK, w.Code)
+
+			start := time.Now()
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+			assert.Equal(t, tc.expectStatus, w.Code)
+			assert.GreaterOrEqual(t, time.Since(start), tc.minElapsed)
+			assert.Less(t, time.Since(start), tc.maxDelay)
+		})
+	}
+}
+
+func TestLimiterUnaryInterceptor_Wait_Deadline(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterUnaryInterceptor(ratelimiter.NewLocal(rate.Every(100*time.Millisecond), 1),
+		env.mockLogger, ratelimiter.WithWait(time.Second, 0))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "success", nil }
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+
+	// The wait is bounded by the deadline of the call.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Less(t, time.Since(start), 20*time.Millisecond)
+
+	// Without a deadline, the call waits for its turn.
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", resp)
+}
+
+func TestLimiterStreamInterceptor_Wait_MaxWaiters(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	interceptor := ratelimiter.LimiterStreamInterceptor(ratelimiter.NewLocal(rate.Every(100*time.Millisecond), 1),
+		env.mockLogger, ratelimiter.WithWait(time.Second, 1))
+	handler := func(srv interface{}, ss grpc.ServerStream) error { return nil }
+	call := func() error {
+		return interceptor(nil, &mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, handler)
+	}
+
+	require.NoError(t, call())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, call(), "the first waiter gets its turn")
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// The single waiting slot is taken.
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call()))
+	wg.Wait()
+}
+
+func TestRedis_Reserve(t *testing.T) {
+	limiter, _ := setUpRedisLimiter(t, 10, 1)
+	ctx := context.Background()
+
+	reservation, err := limiter.Reserve(ctx, "client", time.Second)
+	require.NoError(t, err)
+	assert.True(t, reservation.Allowed)
+	assert.Zero(t, reservation.Delay)
+
+	reservation, err = limiter.Reserve(ctx, "client", time.Second)
+	require.NoError(t, err)
+	assert.True(t, reservation.Allowed)
+	assert.Equal(t, 100*time.Millisecond, reservation.Delay)
+	assert.Equal(t, 0, reservation.Remaining)
+
+	reservation, err = limiter.Reserve(ctx, "client", 150*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, reservation.Allowed)
+	assert.Equal(t, 200*time.Millisecond, reservation.RetryAfter)
+}
@@ -0,0 +1,340 @@
+/*
+Package retry provides gRPC client interceptors retrying failed calls with exponential backoff.
+
+Only calls to idempotent methods are retried, as declared with WithIdempotentMethods or
+WithIdempotent: the server may have processed a failed call, so retrying any other method
+could apply it twice. Calls are retried when they fail with codes.Unavailable or
+codes.ResourceExhausted, the codes of a server that is temporarily down or overloaded.
+
+Delays between attempts come from pkg/backoff. When the server says how long to wait, with
+an errdetails.RetryInfo detail or a retry-after trailer as sent by pkg/middleware/ratelimiter,
+the interceptor waits at least that long. It gives up when the attempts or the backoff are
+exhausted, or when the wait would outlast the call's deadline, and then returns the last error.
+*/
+package retry
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/backoff"
+	"github.com/kmmania/er_commonlib/pkg/logger"
+
+	cbackoff "github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMaxAttempts is the default maximum number of attempts of a call, including the first.
+const DefaultMaxAttempts = 3
+
+// retryAfterTrailer is the trailer in which servers give the number of seconds to wait
+// before retrying.
+const retryAfterTrailer = "retry-after"
+
+// options holds the optional settings of the interceptors.
+type options struct {
+	// idempotent reports whether a full method may be retried.
+	idempotent func(fullMethod string) bool
+	// maxAttempts is the maximum number of attempts of a call.
+	maxAttempts int
+}
+
+// Option configures optional behaviour of the interceptors.
+type Option func(*options)
+
+// WithIdempotentMethods declares methods that may be retried, by full method name such as
+// "/package.Service/Method".
+//
+// Parameters:
+// - methods (...string): The full names of the idempotent methods.
+//
+// Returns:
+// - Option: An option for the interceptors.
+func WithIdempotentMethods(methods ...string) Option {
+	set := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		set[method] = struct{}{}
+	}
+	return WithIdempotent(func(fullMethod string) bool {
+		_, ok := set[fullMethod]
+		return ok
+	})
+}
+
+// WithIdempotent declares the methods that may be retried with a function, e.g. one matching
+// every read-only method of a service.
+//
+// Parameters:
+// - idempotent (func(string) bool): Reports whether the full method may be retried.
+//
+// Returns:
+// - Option: An option for the interceptors.
+func WithIdempotent(idempotent func(fullMethod string) bool) Option {
+	return func(o *options) {
+		o.idempotent = idempotent
+	}
+}
+
+// WithMaxAttempts sets the maximum number of attempts of a call, including the first,
+// DefaultMaxAttempts by default.
+//
+// Parameters:
+// - maxAttempts (int): The maximum number of attempts; values below one are ignored.
+//
+// Returns:
+// - Option: An option for the interceptors.
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(o *options) {
+		if maxAttempts > 0 {
+			o.maxAttempts = maxAttempts
+		}
+	}
+}
+
+// newOptions applies the options.
+func newOptions(opts []Option) *options {
+	o := &options{
+		idempotent:  func(string) bool { return false },
+		maxAttempts: DefaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// RetryUnaryClientInterceptor returns a gRPC UnaryClientInterceptor retrying calls to
+// idempotent methods that fail with `codes.Unavailable` or `codes.ResourceExhausted`.
+//
+// Each retry is logged as a warning (including the full method name, the attempt, the delay
+// and the error). When combined with a client rate limiter, this interceptor should come
+// first, so that every attempt is limited.
+//
+// Parameters:
+// - logger (logger.Logger): The logger instance used to log retries.
+// - opts (...Option): Optional settings; without WithIdempotentMethods or WithIdempotent, no call is retried.
+//
+// Returns:
+//   - grpc.UnaryClientInterceptor: A `grpc.UnaryClientInterceptor` that can be used with the
+//     `grpc.WithUnaryInterceptor` dial option.
+func RetryUnaryClientInterceptor(logger logger.Logger, opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		if !o.idempotent(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+
+		r := o.newRetrier(logger, method)
+		for {
+			var md metadata.MD
+			err := invoker(ctx, method, req, reply, cc, append(callOpts, grpc.Trailer(&md))...)
+			if err == nil || !r.wait(ctx, err, md) {
+				return err
+			}
+		}
+	}
+}
+
+// RetryStreamClientInterceptor returns a gRPC StreamClientInterceptor retrying streams of
+// idempotent methods that fail with `codes.Unavailable` or `codes.ResourceExhausted`.
+//
+// A stream is retried when it fails to open, and, for server-streaming methods, when it
+// fails before the first response: the stream is then opened again and the request sent
+// again. Once a response has been received, or for methods streaming requests, errors are
+// returned to the caller, as replaying the stream could duplicate its messages.
+//
+// Parameters:
+// - logger (logger.Logger): The logger instance used to log retries.
+// - opts (...Option): Optional settings; without WithIdempotentMethods or WithIdempotent, no stream is retried.
+//
+// Returns:
+//   - grpc.StreamClientInterceptor: A `grpc.StreamClientInterceptor` that can be used with the
+//     `grpc.WithStreamInterceptor` dial option.
+func RetryStreamClientInterceptor(logger logger.Logger, opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		if !o.idempotent(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
+		}
+
+		r := o.newRetrier(logger, method)
+		open := func() (grpc.ClientStream, error) {
+			for {
+				stream, err := streamer(ctx, desc, cc, method, callOpts...)
+				if err == nil || !r.wait(ctx, err, nil) {
+					return stream, err
+				}
+			}
+		}
+
+		stream, err := open()
+		if err != nil || desc.ClientStreams {
+			return stream, err
+		}
+		return &retryingClientStream{ClientStream: stream, ctx: ctx, retrier: r, open: open}, nil
+	}
+}
+
+// retrier tracks the attempts of a call.
+type retrier struct {
+	logger      logger.Logger
+	method      string
+	maxAttempts int
+	attempt     int
+	backOff     cbackoff.BackOff
+}
+
+// newRetrier returns a retrier for a call to the method.
+func (o *options) newRetrier(logger logger.Logger, method string) *retrier {
+	return &retrier{
+		logger:      logger,
+		method:      method,
+		maxAttempts: o.maxAttempts,
+		attempt:     1,
+		backOff:     backoff.NewExponentialBackOff(),
+	}
+}
+
+// wait waits before the next attempt of a call that failed with err and the trailer md.
+// It returns false without waiting if the call must not be retried, and false after
+// waiting if the context ended meanwhile.
+func (r *retrier) wait(ctx context.Context, err error, md metadata.MD) bool {
+	if !retryable(err) || r.attempt >= r.maxAttempts {
+		return false
+	}
+	delay := r.backOff.NextBackOff()
+	if delay == cbackoff.Stop {
+		return false
+	}
+	delay = max(delay, serverDelay(err, md))
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+
+	r.attempt++
+	r.logger.Warn("Retrying gRPC call",
+		zap.String("method", r.method),
+		zap.Int("attempt", r.attempt),
+		zap.Duration("delay", delay),
+		zap.Error(err))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryable reports whether a call failing with err may succeed if retried.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// serverDelay returns the delay the server asked to wait before retrying, from the
+// RetryInfo detail of err or else the retry-after trailer; zero if it gave none.
+func serverDelay(err error, md metadata.MD) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	if values := md.Get(retryAfterTrailer); len(values) > 0 {
+		if seconds, err := strconv.Atoi(values[0]); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// retryingClientStream wraps the grpc.ClientStream of a server-streaming call to open it
+// again when it fails before the first response.
+//
+// retryingClientStream embeds the current grpc.ClientStream, replaced on each retry, and
+// overrides SendMsg to keep the request and RecvMsg to retry.
+type retryingClientStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	retrier *retrier
+	open    func() (grpc.ClientStream, error)
+
+	// request is the request sent on the stream.
+	request interface{}
+	// closed reports whether the request side of the stream was closed.
+	closed bool
+	// received reports whether a response was received.
+	received bool
+}
+
+// SendMsg sends the request and keeps it to send it again on retries.
+func (s *retryingClientStream) SendMsg(m interface{}) error {
+	s.request = m
+	return s.ClientStream.SendMsg(m)
+}
+
+// CloseSend closes the request side of the stream.
+func (s *retryingClientStream) CloseSend() error {
+	s.closed = true
+	return s.ClientStream.CloseSend()
+}
+
+// RecvMsg receives a response, opening the stream again if it fails before the first one.
+func (s *retryingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	for err != nil && err != io.EOF && !s.received && s.retrier.wait(s.ctx, err, s.ClientStream.Trailer()) {
+		err = s.reopen()
+		if err == nil {
+			err = s.ClientStream.RecvMsg(m)
+		}
+	}
+	if err == nil {
+		s.received = true
+	}
+	return err
+}
+
+// reopen opens the stream again and replays the request.
+func (s *retryingClientStream) reopen() error {
+	stream, err := s.open()
+	if err != nil {
+		return err
+	}
+	s.ClientStream = stream
+	if s.request != nil {
+		if err := stream.SendMsg(s.request); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	if s.closed {
+		return stream.CloseSend()
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package retry_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/retry"
+	"github.com/kmmania/er_commonlib/pkg/mocks/logger"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+type testEnv struct {
+	ctrl       *gomock.Controller
+	mockLogger *mocks.MockLogger
+}
+
+func setUpTestEnv(t *testing.T) *testEnv {
+	ctrl := gomock.NewController(t)
+	mockLogger := mocks.NewMockLogger(ctrl)
+	mockLogger.EXPECT().Warn("Retrying gRPC call", gomock.Any()).AnyTimes()
+
+	return &testEnv{
+		ctrl:       ctrl,
+		mockLogger: mockLogger,
+	}
+}
+
+func tearDownTestEnv(env *testEnv) {
+	env.ctrl.Finish()
+}
+
+// flakyServer is a health server whose first calls fail.
+type flakyServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	// failures is the number of calls failing before the calls succeed.
+	failures int
+	// err is the error of the failing calls.
+	err error
+	// trailer is the trailer of the failing calls.
+	trailer metadata.MD
+	// calls counts the calls received.
+	calls atomic.Int32
+}
+
+func (s *flakyServer) Check(ctx context.Context, _ *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if int(s.calls.Add(1)) <= s.failures {
+		_ = grpc.SetTrailer(ctx, s.trailer)
+		return nil, s.err
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *flakyServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc.ServerStreamingServer[grpc_health_v1.HealthCheckResponse]) error {
+	if int(s.calls.Add(1)) <= s.failures {
+		stream.SetTrailer(s.trailer)
+		return s.err
+	}
+	return stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+}
+
+// dial serves the server in process and returns a client connected to it through the options.
+func dial(t *testing.T, server grpc_health_v1.HealthServer, opts ...grpc.DialOption) grpc_health_v1.HealthClient {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+// withRetryInfo returns an error with the code and a RetryInfo detail with the delay.
+func withRetryInfo(code codes.Code, delay time.Duration) error {
+	st, _ := status.New(code, "retry later").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	return st.Err()
+}
+
+func TestRetryUnaryClientInterceptor(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	idempotent := retry.WithIdempotentMethods(grpc_health_v1.Health_Check_FullMethodName)
+	testCases := []struct {
+		name        string
+		opts        []retry.Option
+		failures    int
+		err         error
+		expectCode  codes.Code
+		expectCalls int32
+	}{
+		{name: "Retries until success", opts: []retry.Option{idempotent}, failures: 2, err: status.Error(codes.Unavailable, "down"), expectCode: codes.OK, expectCalls: 3},
+		{name: "Retries resource exhausted", opts: []retry.Option{idempotent}, failures: 1, err: status.Error(codes.ResourceExhausted, "busy"), expectCode: codes.OK, expectCalls: 2},
+		{name: "Gives up after max attempts", opts: []retry.Option{idempotent, retry.WithMaxAttempts(2)}, failures: 5, err: status.Error(codes.Unavailable, "down"), expectCode: codes.Unavailable, expectCalls: 2},
+		{name: "Non-retryable code", opts: []retry.Option{idempotent}, failures: 1, err: status.Error(codes.Internal, "boom"), expectCode: codes.Internal, expectCalls: 1},
+		{name: "Non-idempotent method", failures: 1, err: status.Error(codes.Unavailable, "down"), expectCode: codes.Unavailable, expectCalls: 1},
+		{name: "Idempotent function", opts: []retry.Option{retry.WithIdempotent(func(string) bool { return true })}, failures: 1, err: status.Error(codes.Unavailable, "down"), expectCode: codes.OK, expectCalls: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &flakyServer{failures: tc.failures, err: tc.err}
+			client := dial(t, server, grpc.WithUnaryInterceptor(retry.RetryUnaryClientInterceptor(env.mockLogger, tc.opts...)))
+
+			_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			assert.Equal(t, tc.expectCode, status.Code(err))
+			assert.Equal(t, tc.expectCalls, server.calls.Load())
+		})
+	}
+}
+
+func TestRetryUnaryClientInterceptor_ServerDelay(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	testCases := []struct {
+		name        string
+		err         error
+		trailer     metadata.MD
+		timeout     time.Duration
+		expectCode  codes.Code
+		expectCalls int32
+		expectWait  time.Duration
+	}{
+		{name: "Honors RetryInfo", err: withRetryInfo(codes.ResourceExhausted, 300*time.Millisecond), timeout: 5 * time.Second, expectCode: codes.OK, expectCalls: 2, expectWait: 300 * time.Millisecond},
+		{name: "Honors retry-after trailer", err: status.Error(codes.ResourceExhausted, "busy"), trailer: metadata.Pairs("retry-after", "1"), timeout: 5 * time.Second, expectCode: codes.OK, expectCalls: 2, expectWait: time.Second},
+		{name: "Gives up when the delay outlasts the deadline", err: withRetryInfo(codes.Unavailable, 10*time.Second), timeout: time.Second, expectCode: codes.Unavailable, expectCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &flakyServer{failures: 1, err: tc.err, trailer: tc.trailer}
+			client := dial(t, server, grpc.WithUnaryInterceptor(retry.RetryUnaryClientInterceptor(env.mockLogger,
+				retry.WithIdempotentMethods(grpc_health_v1.Health_Check_FullMethodName))))
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			start := time.Now()
+			_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+			assert.Equal(t, tc.expectCode, status.Code(err))
+			assert.Equal(t, tc.expectCalls, server.calls.Load())
+			assert.GreaterOrEqual(t, time.Since(start), tc.expectWait)
+		})
+	}
+}
+
+func TestRetryStreamClientInterceptor(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	testCases := []struct {
+		name        string
+		opts        []retry.Option
+		failures    int
+		expectCode  codes.Code
+		expectCalls int32
+	}{
+		{name: "Retries until the first response", opts: []retry.Option{retry.WithIdempotentMethods(grpc_health_v1.Health_Watch_FullMethodName)}, failures: 2, expectCode: codes.OK, expectCalls: 3},
+		{name: "Non-idempotent method", failures: 1, expectCode: codes.Unavailable, expectCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &flakyServer{failures: tc.failures, err: status.Error(codes.Unavailable, "down")}
+			client := dial(t, server, grpc.WithStreamInterceptor(retry.RetryStreamClientInterceptor(env.mockLogger, tc.opts...)))
+
+			stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+			require.NoError(t, err)
+			resp, err := stream.Recv()
+			assert.Equal(t, tc.expectCode, status.Code(err))
+			if err == nil {
+				assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.GetStatus())
+			}
+			assert.Equal(t, tc.expectCalls, server.calls.Load())
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package timeout
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NoTimeout marks methods and routes that must not be given a timeout, such as long-lived
+// streams. It can be used in a Table or as the timeout of the middleware and interceptors.
+const NoTimeout time.Duration = -1
+
+// Timeouts maps gRPC methods and HTTP routes to timeouts. Each timeout is positive or
+// NoTimeout; methods and routes that are not listed get the timeout given to the middleware
+// or interceptor.
+type Timeouts struct {
+	// GRPC maps gRPC methods to timeouts. Keys are full methods, such as
+	// "/package.Service/Method", or "/package.Service/*" for every method of a service. A
+	// method's own entry takes precedence over its service's.
+	GRPC map[string]time.Duration
+	// HTTP maps Gin routes to timeouts. Keys are route patterns as registered with Gin, such
+	// as "/reports/:id", optionally prefixed with an HTTP method, such as "POST /reports".
+	// An entry with a method takes precedence over one without.
+	HTTP map[string]time.Duration
+}
+
+// Table holds the timeouts of gRPC methods and HTTP routes. Its patterns are parsed once,
+// when it is created, so that looking up a timeout is a map lookup.
+type Table struct {
+	// methods maps full methods to timeouts.
+	methods map[string]time.Duration
+	// services maps services, as "/package.Service/", to timeouts.
+	services map[string]time.Duration
+	// routes maps routes, as "METHOD /path" or "/path", to timeouts.
+	routes map[string]time.Duration
+}
+
+// NewTable validates the timeouts and returns the table resolving them.
+//
+// Parameters:
+// - timeouts (Timeouts): The timeouts of the gRPC methods and HTTP routes.
+//
+// Returns:
+// - *Table: The table.
+// - error: An error if a pattern or a timeout is invalid.
+func NewTable(timeouts Timeouts) (*Table, error) {
+	t := &Table{
+		methods:  make(map[string]time.Duration),
+		services: make(map[string]time.Duration),
+		routes:   make(map[string]time.Duration),
+	}
+
+	for pattern, timeout := range timeouts.GRPC {
+		if err := validate(timeout); err != nil {
+			return nil, fmt.Errorf("timeout: invalid timeout for %q: %w", pattern, err)
+		}
+		i := strings.LastIndex(pattern, "/")
+		if !strings.HasPrefix(pattern, "/") || i <= 1 || i == len(pattern)-1 {
+			return nil, fmt.Errorf("timeout: invalid gRPC pattern %q", pattern)
+		}
+		if service, method := pattern[:i+1], pattern[i+1:]; method == "*" {
+			t.services[service] = timeout
+		} else {
+			t.methods[pattern] = timeout
+		}
+	}
+
+	for pattern, timeout := range timeouts.HTTP {
+		if err := validate(timeout); err != nil {
+			return nil, fmt.Errorf("timeout: invalid timeout for %q: %w", pattern, err)
+		}
+		route := pattern
+		if method, path, ok := strings.Cut(pattern, " "); ok {
+			if method == "" || strings.ToUpper(method) != method {
+				return nil, fmt.Errorf("timeout: invalid HTTP method in pattern %q", pattern)
+			}
+			route = path
+		}
+		if !strings.HasPrefix(route, "/") {
+			return nil, fmt.Errorf("timeout: invalid HTTP pattern %q", pattern)
+		}
+		t.routes[pattern] = timeout
+	}
+
+	return t, nil
+}
+
+// GRPCTimeout returns the timeout of a gRPC method.
+//
+// Parameters:
+// - fullMethod (string): The full method of the call.
+// - fallback (time.Duration): The timeout of methods the table does not list.
+//
+// Returns:
+// - time.Duration: The timeout, or NoTimeout.
+func (t *Table) GRPCTimeout(fullMethod string, fallback time.Duration) time.Duration {
+	if timeout, ok := t.methods[fullMethod]; ok {
+		return timeout
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if timeout, ok := t.services[fullMethod[:i+1]]; ok {
+			return timeout
+		}
+	}
+	return fallback
+}
+
+// HTTPTimeout returns the timeout of an HTTP route.
+//
+// Parameters:
+// - method (string): The HTTP method of the request.
+// - route (string): The route pattern matched by the request, as returned by gin.Context.FullPath; "" if none matched.
+// - fallback (time.Duration): The timeout of routes the table does not list.
+//
+// Returns:
+// - time.Duration: The timeout, or NoTimeout.
+func (t *Table) HTTPTimeout(method, route string, fallback time.Duration) time.Duration {
+	if route == "" {
+		return fallback
+	}
+	if timeout, ok := t.routes[method+" "+route]; ok {
+		return timeout
+	}
+	if timeout, ok := t.routes[route]; ok {
+		return timeout
+	}
+	return fallback
+}
+
+// validate checks that a timeout is positive or NoTimeout.
+func validate(timeout time.Duration) error {
+	if timeout <= 0 && timeout != NoTimeout {
+		return errors.New("must be positive or NoTimeout")
+	}
+	return nil
+}
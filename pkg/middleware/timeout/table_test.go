@@ -0,0 +1,97 @@
+package timeout_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kmmania/er_commonlib/pkg/middleware/timeout"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTable(t *testing.T) {
+	testCases := []struct {
+		name        string
+		timeouts    timeout.Timeouts
+		expectError bool
+	}{
+		{name: "Empty", timeouts: timeout.Timeouts{}},
+		{name: "Valid", timeouts: timeout.Timeouts{
+			GRPC: map[string]time.Duration{"/reports.Service/Generate": 30 * time.Second, "/events.Service/*": timeout.NoTimeout},
+			HTTP: map[string]time.Duration{"POST /reports": 30 * time.Second, "/lookups/:id": 500 * time.Millisecond},
+		}},
+		{name: "Zero timeout", timeouts: timeout.Timeouts{GRPC: map[string]time.Duration{"/svc.Service/Method": 0}}, expectError: true},
+		{name: "Negative timeout", timeouts: timeout.Timeouts{HTTP: map[string]time.Duration{"/path": -time.Second}}, expectError: true},
+		{name: "gRPC pattern without service", timeouts: timeout.Timeouts{GRPC: map[string]time.Duration{"/Method": time.Second}}, expectError: true},
+		{name: "gRPC pattern without method", timeouts: timeout.Timeouts{GRPC: map[string]time.Duration{"/svc.Service/": time.Second}}, expectError: true},
+		{name: "gRPC pattern without slash", timeouts: timeout.Timeouts{GRPC: map[string]time.Duration{"svc.Service/Method": time.Second}}, expectError: true},
+		{name: "HTTP pattern without slash", timeouts: timeout.Timeouts{HTTP: map[string]time.Duration{"reports": time.Second}}, expectError: true},
+		{name: "HTTP pattern with lower-case method", timeouts: timeout.Timeouts{HTTP: map[string]time.Duration{"post /reports": time.Second}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := timeout.NewTable(tc.timeouts)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTable_GRPCTimeout(t *testing.T) {
+	table, err := timeout.NewTable(timeout.Timeouts{GRPC: map[string]time.Duration{
+		"/reports.Service/*":        30 * time.Second,
+		"/reports.Service/Lookup":   500 * time.Millisecond,
+		"/events.Service/Subscribe": timeout.NoTimeout,
+	}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		fullMethod string
+		expected   time.Duration
+	}{
+		{fullMethod: "/reports.Service/Lookup", expected: 500 * time.Millisecond},
+		{fullMethod: "/reports.Service/Generate", expected: 30 * time.Second},
+		{fullMethod: "/events.Service/Subscribe", expected: timeout.NoTimeout},
+		{fullMethod: "/events.Service/Publish", expected: time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.fullMethod, func(t *testing.T) {
+			assert.Equal(t, tc.expected, table.GRPCTimeout(tc.fullMethod, time.Second))
+		})
+	}
+}
+
+func TestTable_HTTPTimeout(t *testing.T) {
+	table, err := timeout.NewTable(timeout.Timeouts{HTTP: map[string]time.Duration{
+		"/reports/:id":  500 * time.Millisecond,
+		"POST /reports": 30 * time.Second,
+		"GET /events":   timeout.NoTimeout,
+	}})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		method   string
+		route    string
+		expected time.Duration
+	}{
+		{name: "Route for any method", method: http.MethodGet, route: "/reports/:id", expected: 500 * time.Millisecond},
+		{name: "Route for its method", method: http.MethodPost, route: "/reports", expected: 30 * time.Second},
+		{name: "Route for another method", method: http.MethodGet, route: "/reports", expected: time.Second},
+		{name: "No timeout", method: http.MethodGet, route: "/events", expected: timeout.NoTimeout},
+		{name: "Unmatched request", method: http.MethodGet, route: "", expected: time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, table.HTTPTimeout(tc.method, tc.route, time.Second))
+		})
+	}
+}
@@ -3,6 +3,10 @@ Package timeout provides HTTP and gRPC middleware interceptors for handling requ
 The package includes both unary and stream interceptors that set a timeout for the context of
 the request. If the request processing exceeds this timeout, the context is canceled automatically
 and returns a timeout error.
+
+By default every request gets the same timeout. With WithTable, a Table gives gRPC methods
+and Gin routes their own timeouts, e.g. 30 seconds for report generation and 500 milliseconds
+for lookups, and NoTimeout exempts long-lived streams.
 */
 package timeout
 
@@ -18,6 +22,54 @@ import (
 	"time"
 )
 
+// options holds the optional settings of the middleware and interceptors.
+type options struct {
+	// table holds per-method and per-route timeouts; nil to apply the same timeout to every request.
+	table *Table
+}
+
+// Option configures optional behaviour of the middleware and interceptors.
+type Option func(*options)
+
+// WithTable applies the timeouts of a table to the methods and routes it lists; the
+// timeout given to the middleware or interceptor remains the default for the others.
+//
+// Parameters:
+// - table (*Table): The table of timeouts, built once at startup with NewTable.
+//
+// Returns:
+// - Option: An option for the middleware and interceptors.
+func WithTable(table *Table) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// newOptions applies the options.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// grpcTimeout returns the timeout of a gRPC method.
+func (o *options) grpcTimeout(fullMethod string, timeout time.Duration) time.Duration {
+	if o.table == nil {
+		return timeout
+	}
+	return o.table.GRPCTimeout(fullMethod, timeout)
+}
+
+// httpTimeout returns the timeout of an HTTP request.
+func (o *options) httpTimeout(c *gin.Context, timeout time.Duration) time.Duration {
+	if o.table == nil {
+		return timeout
+	}
+	return o.table.HTTPTimeout(c.Request.Method, c.FullPath(), timeout)
+}
+
 // TimeoutMiddleware applies a timeout to HTTP requests.
 //
 // This middleware wraps the provided HTTP handler function with a timeout.  If a request
@@ -26,13 +78,21 @@ import (
 // status code and a JSON error message. Otherwise, the request is allowed to proceed.
 //
 // Parameters:
-// - timeout (time.Duration): The maximum duration allowed for the HTTP request.
+// - timeout (time.Duration): The maximum duration allowed for the HTTP request, or NoTimeout.
 // - logger (logger.Logger):  The logger instance used to log timeout events.
+// - opts (...Option): Optional settings such as per-route timeouts.
 //
 // Returns:
 // - gin.HandlerFunc: A `gin.HandlerFunc` suitable for use as middleware in a Gin router.
-func TimeoutMiddleware(timeout time.Duration, logger logger.Logger) gin.HandlerFunc {
+func TimeoutMiddleware(timeout time.Duration, logger logger.Logger, opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts)
 	return func(c *gin.Context) {
+		timeout := o.httpTimeout(c, timeout)
+		if timeout == NoTimeout {
+			c.Next()
+			return
+		}
+
 		// Create a context with timeout.
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
@@ -62,20 +122,27 @@ func TimeoutMiddleware(timeout time.Duration, logger logger.Logger) gin.HandlerF
 // `codes.DeadlineExceeded` error.  Otherwise, the request is allowed to proceed.
 //
 // Parameters:
-//   - timeout (time.Duration): The maximum duration allowed for the gRPC request.
+//   - timeout (time.Duration): The maximum duration allowed for the gRPC request, or NoTimeout.
 //   - logger (logger.Logger):  The logger instance used to log timeout events. Should be a logger
 //     that supports structured logging (e.g., zap, logrus).
+//   - opts (...Option): Optional settings such as per-method timeouts.
 //
 // Returns:
 //   - grpc.UnaryServerInterceptor: A `grpc.UnaryServerInterceptor` that can be used with `grpc.Server`'s
 //     `UnaryInterceptor` option.
-func TimeoutUnaryServerInterceptor(timeout time.Duration, logger logger.Logger) grpc.UnaryServerInterceptor {
+func TimeoutUnaryServerInterceptor(timeout time.Duration, logger logger.Logger, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
+		timeout := o.grpcTimeout(info.FullMethod, timeout)
+		if timeout == NoTimeout {
+			return handler(ctx, req)
+		}
+
 		// Create a context with timeout.
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
@@ -100,19 +167,26 @@ func TimeoutUnaryServerInterceptor(timeout time.Duration, logger logger.Logger)
 // a wrapped `ServerStream` to ensure the timeout context is correctly propagated.
 //
 // Parameters:
-// - timeout (time.Duration): The maximum duration allowed for the gRPC stream.
+// - timeout (time.Duration): The maximum duration allowed for the gRPC stream, or NoTimeout.
 // - logger (logger.Logger):  The logger instance used to log timeout events.
+// - opts (...Option): Optional settings such as per-method timeouts.
 //
 // Returns:
 //   - grpc.StreamServerInterceptor: A `grpc.StreamServerInterceptor` that can be used with `grpc.Server`'s
 //     `StreamInterceptor` option.
-func TimeoutStreamServerInterceptor(timeout time.Duration, logger logger.Logger) grpc.StreamServerInterceptor {
+func TimeoutStreamServerInterceptor(timeout time.Duration, logger logger.Logger, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
 	return func(
 		srv interface{},
 		ss grpc.ServerStream,
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
+		timeout := o.grpcTimeout(info.FullMethod, timeout)
+		if timeout == NoTimeout {
+			return handler(srv, ss)
+		}
+
 		// Create a context with timeout.
 		ctx, cancel := context.WithTimeout(ss.Context(), timeout)
 		defer cancel()
@@ -224,3 +224,97 @@ func (m *mockGRPCServerStream) Context() context.Context {
 	}
 	return context.Background()
 }
+
+func TestTimeoutMiddleware_Table(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	table, err := timeout.NewTable(timeout.Timeouts{HTTP: map[string]time.Duration{
+		"POST /reports/:id": 200 * time.Millisecond,
+		"GET /events":       timeout.NoTimeout,
+	}})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(timeout.TimeoutMiddleware(20*time.Millisecond, env.mockLogger, timeout.WithTable(table)))
+	handler := func(c *gin.Context) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			c.String(http.StatusOK, "OK")
+		case <-c.Request.Context().Done():
+			c.String(http.StatusGatewayTimeout, "Gateway Timeout")
+		}
+	}
+	router.POST("/reports/:id", handler)
+	router.GET("/events", handler)
+	router.GET("/lookups/:id", handler)
+
+	testCases := []struct {
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{method: http.MethodPost, path: "/reports/42", expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/events", expectedStatus: http.StatusOK},
+		{method: http.MethodGet, path: "/lookups/42", expectedStatus: http.StatusGatewayTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestTimeoutServerInterceptors_Table(t *testing.T) {
+	env := setUpTestEnv(t)
+	defer tearDownTestEnv(env)
+
+	table, err := timeout.NewTable(timeout.Timeouts{GRPC: map[string]time.Duration{
+		"/reports.Service/Generate": 200 * time.Millisecond,
+		"/events.Service/*":         timeout.NoTimeout,
+	}})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		fullMethod      string
+		expectDeadline  bool
+		expectRemaining time.Duration
+	}{
+		{fullMethod: "/reports.Service/Generate", expectDeadline: true, expectRemaining: 200 * time.Millisecond},
+		{fullMethod: "/events.Service/Subscribe", expectDeadline: false},
+		{fullMethod: "/lookups.Service/Get", expectDeadline: true, expectRemaining: 20 * time.Millisecond},
+	}
+
+	unary := timeout.TimeoutUnaryServerInterceptor(20*time.Millisecond, env.mockLogger, timeout.WithTable(table))
+	stream := timeout.TimeoutStreamServerInterceptor(20*time.Millisecond, env.mockLogger, timeout.WithTable(table))
+
+	for _, tc := range testCases {
+		t.Run(tc.fullMethod, func(t *testing.T) {
+			assertDeadline := func(ctx context.Context) {
+				deadline, ok := ctx.Deadline()
+				assert.Equal(t, tc.expectDeadline, ok)
+				if ok {
+					assert.InDelta(t, tc.expectRemaining, time.Until(deadline), float64(10*time.Millisecond))
+				}
+			}
+
+			_, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tc.fullMethod},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					assertDeadline(ctx)
+					return nil, nil
+				})
+			assert.NoError(t, err)
+
+			err = stream(nil, &mockGRPCServerStream{}, &grpc.StreamServerInfo{FullMethod: tc.fullMethod},
+				func(srv interface{}, ss grpc.ServerStream) error {
+					assertDeadline(ss.Context())
+					return nil
+				})
+			assert.NoError(t, err)
+		})
+	}
+}
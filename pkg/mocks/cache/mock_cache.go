@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	cache "github.com/kmmania/er_commonlib/pkg/cache"
 	logger "github.com/kmmania/er_commonlib/pkg/logger"
 )
 
@@ -79,6 +80,21 @@ func (mr *MockRedisCacheMockRecorder) GetFromCache(ctx, key, logger, target inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromCache", reflect.TypeOf((*MockRedisCache)(nil).GetFromCache), ctx, key, logger, target)
 }
 
+// GetMany mocks base method.
+func (m *MockRedisCache) GetMany(ctx context.Context, keys []string, destFactory func() interface{}) (*cache.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMany", ctx, keys, destFactory)
+	ret0, _ := ret[0].(*cache.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMany indicates an expected call of GetMany.
+func (mr *MockRedisCacheMockRecorder) GetMany(ctx, keys, destFactory interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMany", reflect.TypeOf((*MockRedisCache)(nil).GetMany), ctx, keys, destFactory)
+}
+
 // InvalidateCache mocks base method.
 func (m *MockRedisCache) InvalidateCache(ctx context.Context, key string, logger logger.Logger) error {
 	m.ctrl.T.Helper()
@@ -93,10 +109,40 @@ func (mr *MockRedisCacheMockRecorder) InvalidateCache(ctx, key, logger interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateCache", reflect.TypeOf((*MockRedisCache)(nil).InvalidateCache), ctx, key, logger)
 }
 
+// InvalidatePrefix mocks base method.
+func (m *MockRedisCache) InvalidatePrefix(ctx context.Context, prefix string, logger logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidatePrefix", ctx, prefix, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidatePrefix indicates an expected call of InvalidatePrefix.
+func (mr *MockRedisCacheMockRecorder) InvalidatePrefix(ctx, prefix, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidatePrefix", reflect.TypeOf((*MockRedisCache)(nil).InvalidatePrefix), ctx, prefix, logger)
+}
+
+// InvalidateTag mocks base method.
+func (m *MockRedisCache) InvalidateTag(ctx context.Context, tag string, logger logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateTag", ctx, tag, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateTag indicates an expected call of InvalidateTag.
+func (mr *MockRedisCacheMockRecorder) InvalidateTag(ctx, tag, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateTag", reflect.TypeOf((*MockRedisCache)(nil).InvalidateTag), ctx, tag, logger)
+}
+
 // Set mocks base method.
-func (m *MockRedisCache) Set(ctx context.Context, key string, value interface{}, ttl, timeout time.Duration) {
+func (m *MockRedisCache) Set(ctx context.Context, key string, value interface{}, ttl, timeout time.Duration) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Set", ctx, key, value, ttl, timeout)
+	ret := m.ctrl.Call(m, "Set", ctx, key, value, ttl, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Set indicates an expected call of Set.
@@ -106,13 +152,62 @@ func (mr *MockRedisCacheMockRecorder) Set(ctx, key, value, ttl, timeout interfac
 }
 
 // SetCache mocks base method.
-func (m *MockRedisCache) SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger) {
+func (m *MockRedisCache) SetCache(ctx context.Context, key string, data interface{}, logger logger.Logger, tags ...string) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SetCache", ctx, key, data, logger)
+	varargs := []interface{}{ctx, key, data, logger}
+	for _, a := range tags {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SetCache", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // SetCache indicates an expected call of SetCache.
-func (mr *MockRedisCacheMockRecorder) SetCache(ctx, key, data, logger interface{}) *gomock.Call {
+func (mr *MockRedisCacheMockRecorder) SetCache(ctx, key, data, logger interface{}, tags ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, key, data, logger}, tags...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCache", reflect.TypeOf((*MockRedisCache)(nil).SetCache), varargs...)
+}
+
+// SetMany mocks base method.
+func (m *MockRedisCache) SetMany(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMany", ctx, values, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMany indicates an expected call of SetMany.
+func (mr *MockRedisCacheMockRecorder) SetMany(ctx, values, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMany", reflect.TypeOf((*MockRedisCache)(nil).SetMany), ctx, values, ttl)
+}
+
+// SetNotFound mocks base method.
+func (m *MockRedisCache) SetNotFound(ctx context.Context, key string, ttl, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNotFound", ctx, key, ttl, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNotFound indicates an expected call of SetNotFound.
+func (mr *MockRedisCacheMockRecorder) SetNotFound(ctx, key, ttl, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNotFound", reflect.TypeOf((*MockRedisCache)(nil).SetNotFound), ctx, key, ttl, timeout)
+}
+
+// SetNotFoundCache mocks base method.
+func (m *MockRedisCache) SetNotFoundCache(ctx context.Context, key string, logger logger.Logger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNotFoundCache", ctx, key, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNotFoundCache indicates an expected call of SetNotFoundCache.
+func (mr *MockRedisCacheMockRecorder) SetNotFoundCache(ctx, key, logger interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCache", reflect.TypeOf((*MockRedisCache)(nil).SetCache), ctx, key, data, logger)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNotFoundCache", reflect.TypeOf((*MockRedisCache)(nil).SetNotFoundCache), ctx, key, logger)
 }